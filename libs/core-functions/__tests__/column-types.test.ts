@@ -0,0 +1,23 @@
+import { addColumnTypeHints, parseColumnTypes, SqlTypeHintPrefix } from "../src/functions/bulker-destination";
+
+test("parse column types", () => {
+  expect(parseColumnTypes(undefined)).toEqual([]);
+  expect(parseColumnTypes("revenue=numeric(38,9)\n\n invalid line \nidentifies.age = int\n=text\nname=")).toEqual([
+    { column: "revenue", type: "numeric(38,9)" },
+    { table: "identifies", column: "age", type: "int" },
+  ]);
+});
+
+test("column type hints are scoped to table", () => {
+  const columnTypes = parseColumnTypes("revenue=numeric(38,9)\nidentifies.age=int");
+  const track: any = { revenue: 1 };
+  addColumnTypeHints(track, "tracks", columnTypes);
+  expect(track).toEqual({ revenue: 1, [SqlTypeHintPrefix + "revenue"]: "numeric(38,9)" });
+
+  const identify: any = {};
+  addColumnTypeHints(identify, "identifies", columnTypes);
+  expect(identify).toEqual({
+    [SqlTypeHintPrefix + "revenue"]: "numeric(38,9)",
+    [SqlTypeHintPrefix + "age"]: "int",
+  });
+});
@@ -0,0 +1,59 @@
+import CustomerioDestination from "../src/functions/customerio-destination";
+import { CustomerioCredentials } from "../src/meta";
+
+type Request = { url: string; method: string; body: any };
+
+async function send(event: any, props: Partial<CustomerioCredentials> = {}): Promise<Request[]> {
+  const requests: Request[] = [];
+  const ctx: any = {
+    props: { siteId: "site", apiKey: "key", region: "US", identifier: "userId", sendPageEvents: false, ...props },
+    log: { debug: () => {}, info: () => {} },
+    fetch: async (url: string, opts: any) => {
+      requests.push({ url, method: opts.method, body: JSON.parse(opts.body) });
+      return { status: 200, text: async () => "" };
+    },
+  };
+  await CustomerioDestination({ messageId: "m1", timestamp: "2024-01-01T00:00:00.000Z", ...event }, ctx);
+  return requests;
+}
+
+test("customerio identify", async () => {
+  const [req] = await send({ type: "identify", userId: "u1", anonymousId: "a1", traits: { email: "a@b.com" } });
+  expect(req.method).toBe("PUT");
+  expect(req.url).toBe("https://track.customer.io/api/v1/customers/u1");
+  expect(req.body).toMatchObject({ email: "a@b.com", anonymous_id: "a1", _timestamp: 1704067200 });
+});
+
+test("customerio track", async () => {
+  const [req] = await send({ type: "track", event: "Order Completed", userId: "u1", properties: { total: 10 } });
+  expect(req.method).toBe("POST");
+  expect(req.url).toBe("https://track.customer.io/api/v1/customers/u1/events");
+  expect(req.body).toMatchObject({ name: "Order Completed", type: "event", data: { total: 10 }, id: "m1" });
+});
+
+test("customerio anonymous track", async () => {
+  const [req] = await send({ type: "track", event: "Signup Started", anonymousId: "a1" }, { region: "EU" });
+  expect(req.url).toBe("https://track-eu.customer.io/api/v1/events");
+  expect(req.body).toMatchObject({ name: "Signup Started", anonymous_id: "a1" });
+  //no identifier and no anonymous id - nothing to send
+  expect(await send({ type: "track", event: "Signup Started" })).toEqual([]);
+});
+
+test("customerio email identifier", async () => {
+  const [identify] = await send(
+    { type: "identify", userId: "u1", traits: { email: "a@b.com" } },
+    { identifier: "email" }
+  );
+  expect(identify.url).toBe("https://track.customer.io/api/v1/customers/a%40b.com");
+  expect(identify.body).toMatchObject({ id: "u1" });
+
+  const [withEmail] = await send(
+    { type: "track", event: "e", userId: "u1", context: { traits: { email: "a@b.com" } } },
+    { identifier: "email" }
+  );
+  expect(withEmail.url).toBe("https://track.customer.io/api/v1/customers/a%40b.com/events");
+
+  //falls back to userId when email is unknown
+  const [withoutEmail] = await send({ type: "track", event: "e", userId: "u1" }, { identifier: "email" });
+  expect(withoutEmail.url).toBe("https://track.customer.io/api/v1/customers/u1/events");
+});
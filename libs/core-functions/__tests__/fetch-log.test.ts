@@ -0,0 +1,49 @@
+import { EventsStore, makeFetch } from "../src/functions/lib";
+
+function collectingStore(): EventsStore & { logged: { level: string; status?: number }[] } {
+  const logged: { level: string; status?: number }[] = [];
+  return {
+    logged,
+    log(connectionId, level, msg) {
+      logged.push({ level, status: msg.status });
+    },
+    close() {},
+  };
+}
+
+async function fetchStatuses(logLevel: "info" | "debug" | "error", statuses: number[]) {
+  const store = collectingStore();
+  const originalFetch = globalThis.fetch;
+  globalThis.fetch = (async (url: string) => new Response("ok", { status: parseInt(url.split("/").pop()!) })) as any;
+  try {
+    const ftch = makeFetch("test-connection", store, logLevel);
+    for (const status of statuses) {
+      await ftch(`https://example.com/${status}`);
+    }
+  } finally {
+    globalThis.fetch = originalFetch;
+  }
+  return store.logged;
+}
+
+test("fetch-log-level-info", async () => {
+  const logged = await fetchStatuses("info", [200, 404, 500]);
+  expect(logged).toEqual([
+    { level: "info", status: 200 },
+    { level: "error", status: 404 },
+    { level: "error", status: 500 },
+  ]);
+});
+
+test("fetch-log-level-error", async () => {
+  const logged = await fetchStatuses("error", [200, 204, 404, 500]);
+  expect(logged).toEqual([
+    { level: "error", status: 404 },
+    { level: "error", status: 500 },
+  ]);
+});
+
+test("fetch-log-level-debug", async () => {
+  const logged = await fetchStatuses("debug", [200, 500]);
+  expect(logged).toEqual([]);
+});
@@ -0,0 +1,47 @@
+import { isRetryableHttpStatus, rejectedRequestError } from "../src/functions/lib";
+import AmplitudeDestination from "../src/functions/amplitude-destination";
+import { RetryErrorName } from "@jitsu/functions-lib";
+
+test("retryable http statuses", () => {
+  for (const status of [401, 403, 408, 429, 500, 502, 503]) {
+    expect(isRetryableHttpStatus(status)).toBe(true);
+  }
+  for (const status of [400, 404, 413, 422]) {
+    expect(isRetryableHttpStatus(status)).toBe(false);
+  }
+});
+
+test("rejected request goes to dead-letter queue", () => {
+  const err = rejectedRequestError("rejected", 400, "bad payload");
+  expect(err.name).toBe(RetryErrorName);
+  expect(err.status).toBe(400);
+  expect(err["retryPolicy"]).toEqual({ retries: 0, delays: [] });
+});
+
+async function amplitudeError(status: number): Promise<any> {
+  const ctx: any = {
+    props: { key: "test" },
+    log: { debug: () => {}, info: () => {} },
+    fetch: async () => ({ status, text: async () => JSON.stringify({ error: "error message" }) }),
+    source: { id: "source" },
+  };
+  const event: any = { type: "identify", userId: "user1", messageId: "1", context: {} };
+  try {
+    await AmplitudeDestination(event, ctx);
+  } catch (e) {
+    return e;
+  }
+  return undefined;
+}
+
+test("amplitude error classification", async () => {
+  const rejected = await amplitudeError(400);
+  expect(rejected.name).toBe(RetryErrorName);
+  expect(rejected.retryPolicy).toEqual({ retries: 0, delays: [] });
+
+  for (const status of [401, 429, 503]) {
+    const retryable = await amplitudeError(status);
+    expect(retryable.name).toBe(RetryErrorName);
+    expect(retryable.retryPolicy).toBeUndefined();
+  }
+});
@@ -0,0 +1,37 @@
+import WebhookDestination, { isSuccessStatus, renderTemplate } from "../src/functions/webhook-destination";
+import { WebhookDestinationConfig } from "../src/meta";
+
+test("webhook-template", () => {
+  const event = { userId: "user 1", context: { traits: { email: "a@b.com" } }, properties: { list: [1, 2] } };
+  expect(renderTemplate("Bearer {{ context.traits.email }}", event)).toBe("Bearer a@b.com");
+  expect(renderTemplate("{{userId}}-{{ missing.field }}", event)).toBe("user 1-");
+  expect(renderTemplate("{{ properties.list }}", event)).toBe("[1,2]");
+  expect(renderTemplate("https://example.com/users/{{ userId }}", event, encodeURIComponent)).toBe(
+    "https://example.com/users/user%201"
+  );
+});
+
+test("webhook-success-status", () => {
+  expect(isSuccessStatus(204)).toBe(true);
+  expect(isSuccessStatus(409)).toBe(false);
+  expect(isSuccessStatus(409, "200-299,409")).toBe(true);
+  expect(isSuccessStatus(201, "200")).toBe(false);
+  expect(isSuccessStatus(500, "200-299, 409")).toBe(false);
+});
+
+test("webhook-response-validation-pattern", () => {
+  const url = "https://example.com";
+  expect(WebhookDestinationConfig.safeParse({ url, responseValidation: "ok|success" }).success).toBe(true);
+  expect(WebhookDestinationConfig.safeParse({ url, responseValidation: "(ok" }).success).toBe(false);
+});
+
+test("webhook-response-validation-full-body", async () => {
+  const body = " ".repeat(300) + '{"status":"ok"}';
+  const ctx: any = {
+    props: { url: "https://example.com", responseValidation: '"status":"ok"' },
+    fetch: async () => ({ status: 200, statusText: "OK", text: async () => body }),
+    log: { debug: () => {} },
+  };
+  const event: any = { type: "track" };
+  await expect(WebhookDestination(event, ctx)).resolves.toBe(event);
+});
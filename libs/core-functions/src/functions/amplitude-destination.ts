@@ -3,7 +3,28 @@ import { RetryError } from "@jitsu/functions-lib";
 import { AnalyticsServerEvent } from "@jitsu/protocols/analytics";
 import { randomUUID } from "crypto";
 import { AmplitudeDestinationConfig } from "../meta";
-import { eventTimeSafeMs, getPageOrScreenProps } from "./lib";
+import { eventTimeSafeMs, getPageOrScreenProps, isRetryableHttpStatus, rejectedRequestError } from "./lib";
+
+/**
+ * Extracts human-readable reason from Amplitude HTTP API error response
+ */
+function amplitudeErrorMessage(responseText: string): string {
+  try {
+    const res = JSON.parse(responseText);
+    const details = [
+      res.missing_field ? `missing field: ${res.missing_field}` : undefined,
+      res.events_with_invalid_fields
+        ? `invalid fields: ${Object.keys(res.events_with_invalid_fields).join(", ")}`
+        : undefined,
+      res.events_with_missing_fields
+        ? `missing fields: ${Object.keys(res.events_with_missing_fields).join(", ")}`
+        : undefined,
+    ].filter(d => !!d);
+    return `${res.error || responseText}${details.length > 0 ? ` (${details.join("; ")})` : ""}`;
+  } catch (e) {
+    return responseText;
+  }
+}
 
 const AmplitudeDestination: JitsuFunction<AnalyticsServerEvent, AmplitudeDestinationConfig> = async (
   event,
@@ -147,10 +168,21 @@ const AmplitudeDestination: JitsuFunction<AnalyticsServerEvent, AmplitudeDestina
       if (res.status === 200) {
         log.debug(`Amplitude ${event.type} OK: ${res.status} message: ${await res.text()}`);
       } else {
-        throw new Error(`Amplitude ${event.type} Error: ${res.status} message: ${await res.text()}`);
+        const text = await res.text();
+        if (!isRetryableHttpStatus(res.status)) {
+          throw rejectedRequestError(
+            `Amplitude ${event.type} rejected: ${res.status} message: ${amplitudeErrorMessage(text)}`,
+            res.status,
+            text
+          );
+        }
+        throw new Error(`Amplitude ${event.type} Error: ${res.status} message: ${text}`);
       }
     }
   } catch (e: any) {
+    if (e instanceof RetryError) {
+      throw e;
+    }
     throw new RetryError(e.message);
   }
 };
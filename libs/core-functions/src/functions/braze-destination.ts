@@ -2,7 +2,7 @@ import { FullContext, JitsuFunction } from "@jitsu/protocols/functions";
 import { RetryError } from "@jitsu/functions-lib";
 import type { AnalyticsServerEvent } from "@jitsu/protocols/analytics";
 import { BrazeCredentials } from "../meta";
-import { eventTimeSafeMs } from "./lib";
+import { eventTimeSafeMs, HttpRequest } from "./lib";
 import omit from "lodash/omit";
 import { pick } from "lodash";
 
@@ -19,12 +19,6 @@ const endpoints = {
   "EU-01 : dashboard-01.braze.eu": "https://rest.fra-01.braze.eu",
   "EU-02 : dashboard-02.braze.eu": "https://rest.fra-02.braze.eu",
 };
-export type HttpRequest = {
-  method?: string;
-  url: string;
-  payload?: any;
-  headers?: Record<string, string>;
-};
 
 function toBrazeGender(gender: string | null | undefined): string | null | undefined {
   if (!gender) {
@@ -335,10 +335,60 @@ export type BulkerDestinationConfig = {
   authToken: string;
   dataLayout?: DataLayoutType;
   keepOriginalNames?: boolean;
+  //SQL types of columns in format `column=type` or `table.column=type`, one per line
+  columnTypes?: string;
 };
 
+export const SqlTypeHintPrefix = "__sql_type_";
+
+export type ColumnType = {
+  //table the type applies to. If not set - type applies to column in any table
+  table?: string;
+  column: string;
+  type: string;
+};
+
+/**
+ * Parses column types overrides from `column=type` or `table.column=type` lines, e.g. `revenue=numeric(38,9)`.
+ * Lines without `=` are ignored
+ */
+export function parseColumnTypes(columnTypes?: string): ColumnType[] {
+  const res: ColumnType[] = [];
+  for (const line of (columnTypes || "").split("\n")) {
+    const idx = line.indexOf("=");
+    if (idx <= 0) {
+      continue;
+    }
+    const name = line.substring(0, idx).trim();
+    const type = line.substring(idx + 1).trim();
+    if (!name || !type) {
+      continue;
+    }
+    const dot = name.lastIndexOf(".");
+    if (dot > 0) {
+      res.push({ table: name.substring(0, dot), column: name.substring(dot + 1), type });
+    } else {
+      res.push({ column: name, type });
+    }
+  }
+  return res;
+}
+
+/**
+ * Adds SQL type hints for the columns that apply to the table
+ */
+export function addColumnTypeHints(event: any, table: string, columnTypes: ColumnType[]) {
+  for (const { table: t, column, type } of columnTypes) {
+    if (!t || t === table) {
+      //bulker uses type hints instead of inferred types when creating columns
+      event[SqlTypeHintPrefix + column] = type;
+    }
+  }
+}
+
 const BulkerDestination: JitsuFunction<AnalyticsServerEvent, BulkerDestinationConfig> = async (event, ctx) => {
   const { bulkerEndpoint, destinationId, authToken, dataLayout = "segment-single-table" } = ctx.props;
+  const columnTypes = parseColumnTypes(ctx.props.columnTypes);
   try {
     const metricsMeta: Omit<MetricsMeta, "messageId"> = {
       workspaceId: ctx.workspace.id,
@@ -363,6 +413,7 @@ const BulkerDestination: JitsuFunction<AnalyticsServerEvent, BulkerDestinationCo
     }
     const events = dataLayouts[dataLayout](adjustedEvent, ctx);
     for (const { event, table } of Array.isArray(events) ? events : [events]) {
+      addColumnTypeHints(event, table, columnTypes);
       const res = await ctx.fetch(
         `${bulkerEndpoint}/post/${destinationId}?tableName=${table}`,
         {
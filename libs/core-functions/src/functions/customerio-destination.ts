@@ -0,0 +1,127 @@
+import { FullContext, JitsuFunction } from "@jitsu/protocols/functions";
+import { RetryError } from "@jitsu/functions-lib";
+import type { AnalyticsServerEvent } from "@jitsu/protocols/analytics";
+import { CustomerioCredentials } from "../meta";
+import { eventTimeSafeMs, getPageOrScreenProps, getTraits, HttpRequest } from "./lib";
+
+const endpoints = {
+  US: "https://track.customer.io/api/v1",
+  EU: "https://track-eu.customer.io/api/v1",
+};
+
+/**
+ * With `email` identifier, events without email trait fall back to userId: identify requests
+ * send userId as `id` attribute, so Customer.io resolves the same person by either of them
+ */
+function getIdentifier(event: AnalyticsServerEvent, ctx: FullContext<CustomerioCredentials>): string | undefined {
+  if (ctx.props.identifier === "email") {
+    return (getTraits(event).email as string | undefined) || event.userId;
+  }
+  return event.userId;
+}
+
+function timestamp(event: AnalyticsServerEvent): number {
+  return Math.floor(eventTimeSafeMs(event) / 1000);
+}
+
+function identifyRequest(
+  event: AnalyticsServerEvent,
+  ctx: FullContext<CustomerioCredentials>,
+  endpoint: string,
+  identifier: string
+): HttpRequest {
+  const traits = getTraits(event);
+  return {
+    method: "PUT",
+    url: `${endpoint}/customers/${encodeURIComponent(identifier)}`,
+    payload: {
+      ...traits,
+      ...(ctx.props.identifier === "email" && event.userId ? { id: event.userId } : {}),
+      //links events previously sent with anonymous_id to the identified person
+      ...(event.anonymousId ? { anonymous_id: event.anonymousId } : {}),
+      _timestamp: timestamp(event),
+    },
+  };
+}
+
+function eventRequest(
+  event: AnalyticsServerEvent,
+  endpoint: string,
+  identifier: string | undefined,
+  name: string,
+  type: "event" | "page" | "screen",
+  data: Record<string, any>
+): HttpRequest | undefined {
+  const payload = {
+    name,
+    type,
+    data,
+    timestamp: timestamp(event),
+    ...(event.messageId ? { id: event.messageId } : {}),
+  };
+  if (identifier) {
+    return { url: `${endpoint}/customers/${encodeURIComponent(identifier)}/events`, payload };
+  } else if (event.anonymousId) {
+    return { url: `${endpoint}/events`, payload: { ...payload, anonymous_id: event.anonymousId } };
+  }
+}
+
+const CustomerioDestination: JitsuFunction<AnalyticsServerEvent, CustomerioCredentials> = async (event, ctx) => {
+  const endpoint = endpoints[ctx.props.region || "US"];
+  const identifier = getIdentifier(event, ctx);
+  const httpRequests: HttpRequest[] = [];
+  if (event.type === "identify") {
+    if (!identifier) {
+      ctx.log.debug(`Customer.io: identify event without ${ctx.props.identifier || "userId"} is skipped`);
+      return;
+    }
+    httpRequests.push(identifyRequest(event, ctx, endpoint, identifier));
+  } else if (event.type === "track" && event.event) {
+    const req = eventRequest(event, endpoint, identifier, event.event, "event", event.properties || {});
+    if (req) {
+      httpRequests.push(req);
+    }
+  } else if ((event.type === "page" || event.type === "screen") && ctx.props.sendPageEvents) {
+    const props = { ...getPageOrScreenProps(event), ...event.properties };
+    //Customer.io expects page url as a name of page event
+    const name = event.type === "page" ? event.context?.page?.url || (props.url as string) : event.name;
+    const req = eventRequest(event, endpoint, identifier, name || event.type, event.type, props);
+    if (req) {
+      httpRequests.push(req);
+    }
+  }
+  if (httpRequests.length === 0) {
+    return;
+  }
+  const auth = Buffer.from(`${ctx.props.siteId}:${ctx.props.apiKey}`).toString("base64");
+  try {
+    for (const httpRequest of httpRequests) {
+      const method = httpRequest.method || "POST";
+      const result = await ctx.fetch(httpRequest.url, {
+        method,
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Basic ${auth}`,
+        },
+        body: JSON.stringify(httpRequest.payload),
+      });
+      if (result.status !== 200) {
+        throw new Error(
+          `Customer.io ${method} ${httpRequest.url}:${JSON.stringify(httpRequest.payload)} --> ${
+            result.status
+          } ${await result.text()}`
+        );
+      } else {
+        ctx.log.debug(`Customer.io ${method} ${httpRequest.url}: ${result.status} ${await result.text()}`);
+      }
+    }
+  } catch (e: any) {
+    throw new RetryError(e.message);
+  }
+};
+
+CustomerioDestination.displayName = "customerio-destination";
+
+CustomerioDestination.description = "This functions covers jitsu events and sends them to Customer.io";
+
+export default CustomerioDestination;
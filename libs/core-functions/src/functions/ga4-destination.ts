@@ -2,7 +2,7 @@ import { JitsuFunction } from "@jitsu/protocols/functions";
 import { RetryError } from "@jitsu/functions-lib";
 import type { AnalyticsServerEvent } from "@jitsu/protocols/analytics";
 import { Ga4Credentials } from "../meta";
-import { createFilter, eventTimeSafeMs } from "./lib";
+import { createFilter, eventTimeSafeMs, isRetryableHttpStatus, rejectedRequestError } from "./lib";
 
 const ReservedUserProperties = [
   "first_open_time",
@@ -368,11 +368,22 @@ const Ga4Destination: JitsuFunction<AnalyticsServerEvent, Ga4Credentials> = asyn
     //   ctx.log.info(`Ga4:${JSON.stringify(gaRequest)} --> ${result.status}: ${await result.text()}`);
     // } else
     if (result.status !== 200 && result.status !== 204) {
-      throw new Error(`Ga4:${JSON.stringify(gaRequest)} --> ${result.status} ${await result.text()}`);
+      const text = await result.text();
+      if (!isRetryableHttpStatus(result.status)) {
+        throw rejectedRequestError(
+          `Ga4:${JSON.stringify(gaRequest)} rejected --> ${result.status}`,
+          result.status,
+          text
+        );
+      }
+      throw new Error(`Ga4:${JSON.stringify(gaRequest)} --> ${result.status} ${text}`);
     } else {
       ctx.log.debug(`Ga4: ${result.status} ${await result.text()}`);
     }
   } catch (e: any) {
+    if (e instanceof RetryError) {
+      throw e;
+    }
     throw new RetryError(`Failed to send request to Ga4: ${JSON.stringify(gaRequest)}: ${e?.message}`);
   }
 };
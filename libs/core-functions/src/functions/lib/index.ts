@@ -29,6 +29,7 @@ import {
   noThrottle,
   stopwatch,
 } from "juava";
+import { RetryError } from "@jitsu/functions-lib";
 
 const log = getLog("functions-context");
 
@@ -70,6 +71,14 @@ export type MetricsMeta = {
   retries?: number;
 };
 
+//request prepared by destination function before sending it with fetch
+export type HttpRequest = {
+  method?: string;
+  url: string;
+  payload?: any;
+  headers?: Record<string, string>;
+};
+
 export type FetchType = (
   url: string,
   opts?: FetchOpts,
@@ -248,6 +257,24 @@ export function eventTimeSafeMs(event: AnalyticsServerEvent) {
   return Math.min(!isNaN(ts) ? ts : now, !isNaN(receivedAt) ? receivedAt : now, now);
 }
 
+/**
+ * Whether request failed with given HTTP status may succeed if repeated later. Other client errors
+ * mean that payload was rejected by the API, so retrying it won't help.
+ * Auth errors are retryable: credentials may be fixed or rotated while event waits for retry
+ */
+export function isRetryableHttpStatus(status: number): boolean {
+  return status < 400 || status >= 500 || status === 401 || status === 403 || status === 408 || status === 429;
+}
+
+/**
+ * Error for request rejected by destination API. Event is put to dead-letter queue without retries
+ */
+export function rejectedRequestError(message: string, status: number, response: string): RetryError {
+  const err = new RetryError({ message, status, response });
+  err["retryPolicy"] = { retries: 0, delays: [] };
+  return err;
+}
+
 export const makeLog = (connectionId: string, eventsStore: EventsStore, repeatToLog?: boolean) => {
   const logFunc = (lb: () => LogMessageBuilder, callback: (l: LogMessageBuilder) => void) => {
     if (repeatToLog) {
@@ -323,10 +350,15 @@ export const makeLog = (connectionId: string, eventsStore: EventsStore, repeatTo
   };
 };
 
+/**
+ * logLevel defines which requests are written to events store: "info" - all requests, "error" - only failed requests
+ * (network errors and non 2xx statuses), "debug" - none unless function is in debug mode.
+ * Level of each record depends on request outcome: "error" for failed requests, "info" otherwise
+ */
 export const makeFetch = (
   connectionId: string,
   eventsStore: EventsStore,
-  logLevel: "info" | "debug",
+  logLevel: "info" | "debug" | "error",
   fetchTimeoutMs: number = 2000
 ) => {
   const throttle = connectionId === "clke5lrfm0000ii0gahryc37d-wbyo-5jyq-KIMXwt" ? getThrottle(5000) : noThrottle();
@@ -341,7 +373,9 @@ export const makeFetch = (
     const ctx = extra?.ctx?.function;
     const id = ctx?.id || "unknown";
     const type = ctx?.type || "unknown";
-    const logEnabled = logLevel === "info" || (ctx?.debugTill && ctx?.debugTill > new Date());
+    const debugEnabled = ctx?.debugTill && ctx?.debugTill > new Date();
+    const logEnabled = logLevel !== "debug" || debugEnabled;
+    const errorsOnly = logLevel === "error" && !debugEnabled;
     const logToRedis = typeof extra?.log === "boolean" ? extra.log : true;
     const baseInfo =
       logEnabled && logToRedis
@@ -383,7 +417,7 @@ export const makeFetch = (
       if (logEnabled) {
         const elapsedMs = sw.elapsedMs();
         if (logToRedis) {
-          eventsStore.log(connectionId, logLevel, { ...baseInfo, error: getErrorMessage(err), elapsedMs: elapsedMs });
+          eventsStore.log(connectionId, "error", { ...baseInfo, error: getErrorMessage(err), elapsedMs: elapsedMs });
         }
         log.inDebug(l =>
           l.log(
@@ -401,8 +435,8 @@ export const makeFetch = (
       //clone response to be able to read it twice
       const cloned = fetchResult.clone();
       const respText = await trimResponse(cloned);
-      if (logToRedis) {
-        eventsStore.log(connectionId, logLevel, {
+      if (logToRedis && (!errorsOnly || fetchResult.status >= 300)) {
+        eventsStore.log(connectionId, fetchResult.status >= 300 ? "error" : "info", {
           ...baseInfo,
           status: fetchResult.status,
           statusText: fetchResult.statusText,
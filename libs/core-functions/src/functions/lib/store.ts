@@ -1,5 +1,5 @@
 import { SetOpts, TTLStore } from "@jitsu/protocols/functions";
-import type { Redis } from "ioredis";
+import type { Cluster, Redis } from "ioredis";
 import parse from "parse-duration";
 import { MongoClient, ReadPreference, Collection } from "mongodb";
 import { RetryError } from "@jitsu/functions-lib";
@@ -25,7 +25,7 @@ function getTtlSec(opts?: SetOpts): number {
   return Math.min(seconds, maxAllowedTTL);
 }
 
-export const createTtlStore = (namespace: string, redisClient: Redis): TTLStore => ({
+export const createTtlStore = (namespace: string, redisClient: Redis | Cluster): TTLStore => ({
   get: async (key: string) => {
     const res = await redisClient.get(`store:${namespace}:${key}`);
     return res ? JSON.parse(res) : undefined;
@@ -2,30 +2,66 @@ import { JitsuFunction } from "@jitsu/protocols/functions";
 import { HTTPError, RetryError } from "@jitsu/functions-lib";
 import type { AnalyticsServerEvent } from "@jitsu/protocols/analytics";
 import { WebhookDestinationConfig } from "../meta";
+import get from "lodash/get";
+
+/**
+ * Replaces `{{ path.to.field }}` placeholders with values of corresponding event fields.
+ * Missing fields are replaced with empty string, objects are serialized to JSON
+ */
+export function renderTemplate(template: string, event: any, encode?: (s: string) => string): string {
+  return template.replace(/\{\{\s*([\w.$\[\]-]+)\s*\}\}/g, (_, path) => {
+    const value = get(event, path);
+    const str =
+      value === undefined || value === null ? "" : typeof value === "object" ? JSON.stringify(value) : `${value}`;
+    return encode ? encode(str) : str;
+  });
+}
+
+/**
+ * Checks status against comma separated list of codes and ranges, e.g. `200-299,409`
+ */
+export function isSuccessStatus(status: number, successStatusCodes?: string): boolean {
+  if (!successStatusCodes?.trim()) {
+    return status >= 200 && status < 300;
+  }
+  return successStatusCodes.split(",").some(s => {
+    const [from, to] = s.split("-").map(c => parseInt(c.trim()));
+    return isNaN(to) ? status === from : status >= from && status <= to;
+  });
+}
 
 const WebhookDestination: JitsuFunction<AnalyticsServerEvent, WebhookDestinationConfig> = async (event, ctx) => {
   try {
     const headers = ctx.props.headers || [];
-    const res = await ctx.fetch(ctx.props.url, {
+    const res = await ctx.fetch(renderTemplate(ctx.props.url, event, encodeURIComponent), {
       method: ctx.props.method || "POST",
       body: JSON.stringify(event),
       headers: {
         "Content-Type": "application/json",
         ...headers.reduce((res, header) => {
-          const [key, value] = header.split(":");
+          const idx = header.indexOf(":");
+          if (idx <= 0) {
+            return res;
+          }
+          const key = header.substring(0, idx).trim();
+          const value = renderTemplate(header.substring(idx + 1).trim(), event);
           return { ...res, [key]: value };
         }, {}),
       },
     });
-    if (!res.ok) {
+    const fullResponse = (await res.text()) || "";
+    const responseText = fullResponse.substring(0, 255);
+    if (!isSuccessStatus(res.status, ctx.props.successStatusCodes)) {
+      throw new HTTPError(`HTTP Error: ${res.status} ${res.statusText}`, res.status, responseText);
+    }
+    if (ctx.props.responseValidation && !new RegExp(ctx.props.responseValidation).test(fullResponse)) {
       throw new HTTPError(
-        `HTTP Error: ${res.status} ${res.statusText}`,
+        `Response doesn't match validation pattern: ${ctx.props.responseValidation}`,
         res.status,
-        (await res.text())?.substring(0, 255)
+        responseText
       );
-    } else {
-      ctx.log.debug(`HTTP Status: ${res.status} ${res.statusText} Response: ${(await res.text())?.substring(0, 255)}`);
     }
+    ctx.log.debug(`HTTP Status: ${res.status} ${res.statusText} Response: ${responseText}`);
     return event;
   } catch (e: any) {
     throw new RetryError(e.message);
@@ -19,6 +19,7 @@ import FacebookConversionsApi from "./functions/facebook-conversions";
 import IntercomDestination from "./functions/intercom-destination";
 import HubspotDestination from "./functions/hubspot-destination";
 import BrazeDestination from "./functions/braze-destination";
+import CustomerioDestination from "./functions/customerio-destination";
 
 const builtinDestinations: Record<BuiltinDestinationFunctionName, JitsuFunction> = {
   "builtin.destination.bulker": BulkerDestination as JitsuFunction,
@@ -27,6 +28,7 @@ const builtinDestinations: Record<BuiltinDestinationFunctionName, JitsuFunction>
   "builtin.destination.segment-proxy": SegmentDestination as JitsuFunction,
   "builtin.destination.june": JuneDestination as JitsuFunction,
   "builtin.destination.braze": BrazeDestination as JitsuFunction,
+  "builtin.destination.customerio": CustomerioDestination as JitsuFunction,
   "builtin.destination.ga4": Ga4Destination as JitsuFunction,
   "builtin.destination.webhook": WebhookDestination as JitsuFunction,
   "builtin.destination.posthog": PosthogDestination as JitsuFunction,
@@ -96,3 +98,4 @@ export * from "./functions/lib/crypto-code";
 export * from "./lib/inmem-store";
 export * from "./lib/config-types";
 export * from "./lib/entity-store";
+export { ConnectionRetryLimits } from "./meta";
@@ -29,13 +29,46 @@ export const FacebookConversionApiCredentialsUi = {
 
 export type FacebookConversionApiCredentials = z.infer<typeof FacebookConversionApiCredentials>;
 
+function isValidRegex(pattern?: string): boolean {
+  if (!pattern) {
+    return true;
+  }
+  try {
+    new RegExp(pattern);
+    return true;
+  } catch (e) {
+    return false;
+  }
+}
+
 export const WebhookDestinationConfig = z.object({
-  url: z.string().url().describe("Webhook URL"),
+  url: z
+    .string()
+    .url()
+    .describe("Webhook URL. May refer to event fields with <code>{{ path.to.field }}</code> placeholders"),
   method: z
     .enum(["GET", "POST", "PUT", "DELETE"])
     .default("POST")
     .describe("HTTP method. Can be <code>GET</code>, <code>POST</code>, <code>PUT</code>, <code>DELETE</code>"),
-  headers: z.array(z.string()).optional().describe("List of headers in format <code>key: value</code>"),
+  headers: z
+    .array(z.string())
+    .optional()
+    .describe(
+      "List of headers in format <code>key: value</code>. Values may refer to event fields with <code>{{ path.to.field }}</code> placeholders, e.g. <code>X-User-Id: {{ userId }}</code>"
+    ),
+  successStatusCodes: z
+    .string()
+    .optional()
+    .describe(
+      "Success Status Codes::Comma separated list of HTTP status codes or ranges treated as success, e.g. <code>200-299,409</code>. Default: <code>200-299</code>"
+    ),
+  responseValidation: z
+    .string()
+    .optional()
+    .refine(isValidRegex, { message: "Invalid regular expression" })
+    .describe(
+      "Response Validation::Regular expression that response body must match for request to be considered successful. Leave empty to skip validation"
+    ),
 });
 
 export type WebhookDestinationConfig = z.infer<typeof WebhookDestinationConfig>;
@@ -177,6 +210,37 @@ export const BrazeCredentials = z.object({
 });
 export type BrazeCredentials = z.infer<typeof BrazeCredentials>;
 
+export const CustomerioCredentials = z.object({
+  siteId: z
+    .string()
+    .describe("Site ID::Can be found in Customer.io under <b>Settings > Workspace Settings > API Credentials</b>"),
+  apiKey: z.string().describe("API Key::Tracking API Key from <b>Settings > Workspace Settings > API Credentials</b>"),
+  region: z
+    .enum(["US", "EU"])
+    .optional()
+    .default("US")
+    .describe("Region::Region of your Customer.io account data center"),
+  identifier: z
+    .enum(["userId", "email"])
+    .optional()
+    .default("userId")
+    .describe(
+      "Identifier::Which field identifies people in Customer.io: <code>userId</code> or <code>email</code> trait. With <code>email</code>, events without email trait are sent with <code>userId</code>. Events without identifier are sent as anonymous events"
+    ),
+  sendPageEvents: z
+    .boolean()
+    .optional()
+    .default(false)
+    .describe("Send <code>page</code> and <code>screen</code> events to Customer.io"),
+});
+export type CustomerioCredentials = z.infer<typeof CustomerioCredentials>;
+
+export const CustomerioCredentialsUi = {
+  apiKey: {
+    password: true,
+  },
+};
+
 export const SegmentCredentials = z.object({
   apiBase: z.string().default("https://api.segment.io/v1").describe("API Base::Segment API Base"),
   writeKey: z
@@ -331,3 +395,16 @@ export const HubspotCredentials = z.object({
 });
 
 export type HubspotCredentials = z.infer<typeof HubspotCredentials>;
+
+//Limits of per-connection retry settings. Console validates connection options against them, rotor enforces them
+export const ConnectionRetryLimits = {
+  maxRetries: 10,
+  maxBackoffBase: 60,
+} as const;
+
+export const ConnectionRetryOptions = z.object({
+  retries: z.number().int().min(0).max(ConnectionRetryLimits.maxRetries).optional(),
+  retryBackoffBase: z.number().int().min(1).max(ConnectionRetryLimits.maxBackoffBase).optional(),
+});
+
+export type ConnectionRetryOptions = z.infer<typeof ConnectionRetryOptions>;
@@ -0,0 +1,19 @@
+import { expect, test } from "@jest/globals";
+import { createGeoCache } from "../src/lib/maxmind";
+
+test("Geo cache is bounded and evicts least recently used entries", () => {
+  const cache = createGeoCache(3);
+  cache.set("1.1.1.1", { country: { code: "US", name: "United States", isEU: false } });
+  cache.set("2.2.2.2", {});
+  cache.set("3.3.3.3", {});
+  //hit makes entry recently used
+  expect(cache.get("1.1.1.1")).toEqual({ country: { code: "US", name: "United States", isEU: false } });
+  cache.set("4.4.4.4", {});
+  cache.set("5.5.5.5", {});
+
+  expect(cache.size).toBe(3);
+  expect(cache.has("1.1.1.1")).toBe(true);
+  expect(cache.has("2.2.2.2")).toBe(false);
+  expect(cache.has("3.3.3.3")).toBe(false);
+  expect(cache.has("5.5.5.5")).toBe(true);
+});
@@ -0,0 +1,18 @@
+import { expect, test } from "@jest/globals";
+import { healthStatus } from "../src/lib/health";
+
+test("Health status is derived from subsystems", () => {
+  const ok = { kafka: { state: "running" as const }, mongodb: { status: "ok" as const }, stores: ["ok", "ok"] };
+  expect(healthStatus(ok)).toBe("pass");
+  expect(healthStatus({ ...ok, redis: "ready" })).toBe("pass");
+  //http-only mode has no kafka consumer
+  expect(healthStatus({ ...ok, kafka: undefined })).toBe("pass");
+
+  expect(healthStatus({ ...ok, redis: "reconnecting" })).toBe("warn");
+  expect(healthStatus({ ...ok, stores: ["ok", "outdated"] })).toBe("warn");
+  expect(healthStatus({ ...ok, kafka: { state: "connecting" } })).toBe("warn");
+
+  expect(healthStatus({ ...ok, kafka: { state: "crashed", error: "boom" } })).toBe("fail");
+  expect(healthStatus({ ...ok, mongodb: { status: "error", error: "ping timeout" } })).toBe("fail");
+  expect(healthStatus({ ...ok, stores: ["failed", "ok"] })).toBe("fail");
+});
@@ -0,0 +1,34 @@
+import { afterEach, expect, test } from "@jest/globals";
+import { createHash } from "juava";
+import { checkMetricsAuth } from "../src/lib/metrics-auth";
+
+afterEach(() => {
+  delete process.env.ROTOR_METRICS_AUTH_TOKENS;
+  delete process.env.ROTOR_METRICS_RAW_AUTH_TOKENS;
+});
+
+test("Metrics endpoint is open when no tokens are configured", () => {
+  expect(checkMetricsAuth("")).toBe(true);
+});
+
+test("Metrics endpoint accepts hashed tokens", () => {
+  process.env.ROTOR_METRICS_AUTH_TOKENS = createHash("secret-token");
+  expect(checkMetricsAuth("Bearer secret-token")).toBe(true);
+  expect(checkMetricsAuth("Bearer wrong-token")).toBe(false);
+  //hash itself is not a valid token
+  expect(checkMetricsAuth(`Bearer ${process.env.ROTOR_METRICS_AUTH_TOKENS}`)).toBe(false);
+  expect(checkMetricsAuth("")).toBe(false);
+});
+
+test("Metrics endpoint accepts raw tokens", () => {
+  process.env.ROTOR_METRICS_RAW_AUTH_TOKENS = "token1,token2";
+  expect(checkMetricsAuth("Bearer token2")).toBe(true);
+  expect(checkMetricsAuth("Bearer token3")).toBe(false);
+  expect(checkMetricsAuth("token1")).toBe(false);
+});
+
+test("Metrics endpoint ignores empty tokens", () => {
+  process.env.ROTOR_METRICS_RAW_AUTH_TOKENS = "token1,";
+  expect(checkMetricsAuth("Bearer ")).toBe(false);
+  expect(checkMetricsAuth("Bearer token1")).toBe(true);
+});
@@ -0,0 +1,30 @@
+import { expect, test } from "@jest/globals";
+import { createRateLimiter, getRateLimiter } from "../src/lib/rate-limiter";
+
+test("Rate limiter allows burst up to the limit", () => {
+  const limiter = createRateLimiter(5);
+  for (let i = 0; i < 5; i++) {
+    expect(limiter.tryAcquire()).toBe(true);
+  }
+  expect(limiter.tryAcquire()).toBe(false);
+});
+
+test("Rate limiter waits for the next token", async () => {
+  const limiter = createRateLimiter(10);
+  for (let i = 0; i < 10; i++) {
+    limiter.tryAcquire();
+  }
+  const start = Date.now();
+  await limiter.acquire();
+  expect(Date.now() - start).toBeGreaterThanOrEqual(50);
+});
+
+test("Rate limiters are kept per key", () => {
+  const limiter = getRateLimiter("conn1.events", 1);
+  expect(limiter).toBeDefined();
+  expect(getRateLimiter("conn1.events", 1)).toBe(limiter);
+  expect(getRateLimiter("conn2.events", 1)).not.toBe(limiter);
+  //limit change creates new limiter
+  expect(getRateLimiter("conn1.events", 2)).not.toBe(limiter);
+  expect(getRateLimiter("conn1.events", undefined)).toBeUndefined();
+});
@@ -0,0 +1,25 @@
+import { expect, test } from "@jest/globals";
+import { RetryError } from "@jitsu/functions-lib";
+import { connectionRetryPolicy, getRetryPolicy, retryDefaultPolicy } from "../src/lib/retries";
+
+test("Default retry policy", () => {
+  expect(connectionRetryPolicy()).toEqual(retryDefaultPolicy);
+  expect(connectionRetryPolicy({})).toEqual(retryDefaultPolicy);
+});
+
+test("Connection retry policy", () => {
+  expect(connectionRetryPolicy({ retries: 2, retryBackoffBase: 5 })).toEqual({ retries: 2, delays: [5, 25] });
+  expect(connectionRetryPolicy({ retries: 0 })).toEqual({ retries: 0, delays: [] });
+  //number of retries is capped
+  expect(connectionRetryPolicy({ retries: 100 }).retries).toBe(10);
+  //delays are capped by max delay
+  expect(connectionRetryPolicy({ retries: 4, retryBackoffBase: 60 }).delays).toEqual([60, 1440, 1440, 1440]);
+});
+
+test("Function retry policy can't exceed connection policy", () => {
+  const err: any = new RetryError("test");
+  err.retryPolicy = { retries: 5, delays: [1, 2, 3, 4, 5] };
+  expect(getRetryPolicy(err, { retries: 2 }).retries).toBe(2);
+  err.retryPolicy = { retries: 1, delays: [1] };
+  expect(getRetryPolicy(err, { retries: 2 })).toEqual({ retries: 1, delays: [1] });
+});
@@ -24,12 +24,19 @@ export const FunctionsHandler =
   async (req, res) => {
     const message = req.body as IngestMessage;
     //log.atInfo().log(`Functions handler. Message ID: ${message.messageId} connectionId: ${message.connectionId}`);
-    const result = await rotorMessageHandler(message, {
-      ...rotorContext,
-      connectionStore: requireDefined(connectionsStore.getCurrent(), "Connection store is not initialized"),
-      functionsStore: requireDefined(functionsStore.getCurrent(), "Functions store is not initialized"),
-      workspaceStore: requireDefined(workspaceStore.getCurrent(), "Workspace store is not initialized"),
-    });
+    const result = await rotorMessageHandler(
+      message,
+      {
+        ...rotorContext,
+        connectionStore: requireDefined(connectionsStore.getCurrent(), "Connection store is not initialized"),
+        functionsStore: requireDefined(functionsStore.getCurrent(), "Functions store is not initialized"),
+        workspaceStore: requireDefined(workspaceStore.getCurrent(), "Workspace store is not initialized"),
+      },
+      "all",
+      undefined,
+      //http requests can't be requeued
+      false
+    );
     if (result?.events && result.events.length > 0) {
       res.json(result.events);
     } else {
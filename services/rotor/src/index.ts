@@ -1,22 +1,23 @@
 import { checkHash, checkRawToken, disableService, getLog, setServerJsonFormat, isTruish } from "juava";
 import { destinationMessagesTopic, getCredentialsFromEnv, rotorConsumerGroupId } from "./lib/kafka-config";
-import { kafkaRotor } from "./lib/rotor";
+import { KafkaRotor, kafkaRotor } from "./lib/rotor";
 import { createClickhouseLogger, DummyEventsStore, EventsStore, mongodb } from "@jitsu/core-functions";
 import express from "express";
 import { UDFRunHandler } from "./http/udf";
 import Prometheus from "prom-client";
 import { FunctionsHandler, FunctionsHandlerMulti } from "./http/functions";
-import { initMaxMindClient, GeoResolver } from "./lib/maxmind";
+import { initMaxMindClient, GeoResolver, maxMindBuildDates, maxMindLastUpdate } from "./lib/maxmind";
 import { MessageHandlerContext, rotorMessageHandler } from "./lib/message-handler";
 import { DummyMetrics, Metrics } from "./lib/metrics";
 import { connectionsStore, functionsStore } from "./lib/repositories";
 import { Server } from "node:net";
 import { getApplicationVersion, getDiagnostics } from "./lib/version";
-import { Redis } from "ioredis";
-import { createRedis } from "./lib/redis";
+import { createRedis, isRedisConfigured, RedisClient } from "./lib/redis";
 import * as util from "util";
 import { getHeapSnapshot } from "node:v8";
 import { ProfileUDFRunHandler } from "./http/profiles-udf";
+import { checkMetricsAuth } from "./lib/metrics-auth";
+import { healthStatus, mongodbHealth, startMongodbHealthCheck } from "./lib/health";
 const log = getLog("rotor");
 
 disableService("prisma");
@@ -53,16 +54,17 @@ async function main() {
   let metricsServer: Server | undefined;
   let geoResolver: GeoResolver;
   let eventsLogger: EventsStore;
-  let redisClient: Redis | undefined;
+  let redisClient: RedisClient | undefined;
   try {
     Prometheus.collectDefaultMetrics();
     await mongodb.waitInit();
+    startMongodbHealthCheck();
     if (process.env.CLICKHOUSE_HOST || process.env.CLICKHOUSE_URL) {
       eventsLogger = createClickhouseLogger();
     } else {
       eventsLogger = DummyEventsStore;
     }
-    if (process.env.REDIS_URL) {
+    if (isRedisConfigured()) {
       redisClient = createRedis();
     }
 
@@ -130,7 +132,7 @@ async function main() {
       .start()
       .then(chMetrics => {
         log.atInfo().log(`Kafka processing started. Listening for topics ${kafkaTopics} with group ${consumerGroupId}`);
-        httpServer = initHTTP({ eventsLogger, metrics: chMetrics, geoResolver, redisClient });
+        httpServer = initHTTP({ eventsLogger, metrics: chMetrics, geoResolver, redisClient }, rotor);
       })
       .catch(async e => {
         log.atError().withCause(e).log("Failed to start rotor processing");
@@ -154,7 +156,10 @@ async function main() {
   }
 }
 
-function initHTTP(rotorContext: Omit<MessageHandlerContext, "connectionStore" | "functionsStore" | "workspaceStore">) {
+function initHTTP(
+  rotorContext: Omit<MessageHandlerContext, "connectionStore" | "functionsStore" | "workspaceStore">,
+  rotor?: KafkaRotor
+) {
   http.use((req, res, next) => {
     if (req.path === "/health" || req.path === "/version") {
       return next();
@@ -190,9 +195,17 @@ function initHTTP(rotorContext: Omit<MessageHandlerContext, "connectionStore" |
       diagnostics: isTruish(process.env.__DANGEROUS_ENABLE_FULL_DIAGNOSTICS) ? getDiagnostics() : undefined,
     });
   });
-  http.get("/health", (req, res) => {
-    res.json({
-      status: "pass",
+  const health = () => {
+    const kafka = rotor?.consumerStatus();
+    const mongodb = mongodbHealth();
+    const redis = rotorContext.redisClient?.status;
+    return {
+      status: healthStatus({
+        kafka,
+        mongodb,
+        redis,
+        stores: [connectionsStore.status(), functionsStore.status()],
+      }),
       connectionsStore: {
         enabled: connectionsStore.getCurrent()?.enabled || "loading",
         status: connectionsStore.status(),
@@ -205,6 +218,26 @@ function initHTTP(rotorContext: Omit<MessageHandlerContext, "connectionStore" |
         lastUpdated: functionsStore.lastRefresh(),
         lastModified: functionsStore.lastModified(),
       },
+      geo: {
+        enabled: !!maxMindLastUpdate(),
+        lastUpdated: maxMindLastUpdate(),
+        databases: maxMindBuildDates(),
+      },
+      redis: redis ? { status: redis } : undefined,
+      mongodb,
+      kafka: kafka ? { consumer: kafka } : undefined,
+    };
+  };
+  //public endpoint for probes: cheap and doesn't reveal workspace data
+  http.get("/health", (req, res) => {
+    res.json(health());
+  });
+  //requires auth: includes per-connection details
+  http.get("/status", (req, res) => {
+    const h = health();
+    res.json({
+      ...h,
+      kafka: rotor ? { ...h.kafka, lastProcessed: rotor.lastProcessed() } : undefined,
     });
   });
   http.post("/udfrun", UDFRunHandler);
@@ -234,6 +267,13 @@ function initHTTP(rotorContext: Omit<MessageHandlerContext, "connectionStore" |
 }
 
 function initMetricsServer() {
+  metricsHttp.use((req, res, next) => {
+    if (!checkMetricsAuth(req.headers.authorization || "")) {
+      res.status(401).json({ error: "Authorization header with valid Bearer token is required" });
+      return;
+    }
+    next();
+  });
   metricsHttp.get("/metrics", async (req, res) => {
     res.set("Content-Type", Prometheus.register.contentType);
     const result = await Prometheus.register.metrics();
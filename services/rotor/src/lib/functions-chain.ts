@@ -26,6 +26,8 @@ import { retryObject } from "./retries";
 import NodeCache from "node-cache";
 import isEqual from "lodash/isEqual";
 import { MessageHandlerContext } from "./message-handler";
+import { withRecognitionMetrics } from "./recognition-metrics";
+import { getRateLimiter, rateLimitedFetch, RateLimiter } from "./rate-limiter";
 
 const fastStoreWorkspaceId = (process.env.FAST_STORE_WORKSPACE_ID ?? "").split(",").filter(x => x.length > 0);
 
@@ -39,6 +41,8 @@ export type Func = {
 export type FuncChain = {
   context: FunctionChainContext;
   functions: Func[];
+  //limits number of events processed per second by the chain
+  eventsLimiter?: RateLimiter;
 };
 
 export type FuncChainFilter = "all" | "udf-n-dst" | "dst-only";
@@ -118,6 +122,7 @@ export function buildFunctionChain(
         authToken: bulkerAuthKey,
         dataLayout: connectionData.dataLayout ?? "segment-single-table",
         keepOriginalNames: connectionData.keepOriginalNames,
+        columnTypes: connectionData.columnTypes,
       },
     };
   } else {
@@ -146,10 +151,13 @@ export function buildFunctionChain(
     }
   }
   const chainCtx: FunctionChainContext = {
-    fetch: makeFetch(connection.id, rotorContext.eventsLogger, connectionData.fetchLogLevel || "info", fetchTimeoutMs),
+    fetch: rateLimitedFetch(
+      makeFetch(connection.id, rotorContext.eventsLogger, connectionData.fetchLogLevel || "info", fetchTimeoutMs),
+      getRateLimiter(`${connection.id}.requests`, connectionData.maxRequestsPerSecond)
+    ),
     log: makeLog(connection.id, rotorContext.eventsLogger),
     store,
-    anonymousEventsStore,
+    anonymousEventsStore: withRecognitionMetrics(anonymousEventsStore, connection.id),
     connectionOptions: connectionData,
   };
 
@@ -272,6 +280,7 @@ export function buildFunctionChain(
   return {
     functions: funcs,
     context: chainCtx,
+    eventsLimiter: getRateLimiter(`${connection.id}.events`, connectionData.maxEventsPerSecond),
   };
 }
 
@@ -340,7 +349,9 @@ export async function runChain(
         });
         functionsTime.observe({ connectionId: eventContext.connection?.id ?? "", functionId: f.id }, ms);
         const args = [err?.name, err?.message];
-        const r = retriesEnabled ? retryObject(err, eventContext.retries ?? 0) : undefined;
+        const r = retriesEnabled
+          ? retryObject(err, eventContext.retries ?? 0, chain.context.connectionOptions)
+          : undefined;
         if (r) {
           args.push(r);
         }
@@ -0,0 +1,75 @@
+import { mongodb } from "@jitsu/core-functions";
+import { getLog, parseNumber } from "juava";
+import type { KafkaConsumerStatus } from "./rotor";
+
+const log = getLog("health");
+
+export type HealthStatus = "pass" | "warn" | "fail";
+
+export type MongodbHealth = {
+  status: "unknown" | "ok" | "error";
+  latencyMs?: number;
+  lastChecked?: Date;
+  error?: string;
+};
+
+const mongodbCheckIntervalMs = 1000 * parseNumber(process.env.MONGODB_HEALTH_CHECK_INTERVAL_SEC, 30);
+const mongodbPingTimeoutMs = 1000;
+
+let mongodbState: MongodbHealth = { status: "unknown" };
+
+async function pingMongodb() {
+  const start = Date.now();
+  let timer: any;
+  try {
+    await Promise.race([
+      mongodb().db().command({ ping: 1 }),
+      new Promise((_, reject) => {
+        timer = setTimeout(() => reject(new Error(`ping timeout ${mongodbPingTimeoutMs}ms`)), mongodbPingTimeoutMs);
+      }),
+    ]);
+    mongodbState = { status: "ok", latencyMs: Date.now() - start, lastChecked: new Date() };
+  } catch (e: any) {
+    log.atWarn().log(`MongoDB health check failed: ${e?.message}`);
+    mongodbState = { status: "error", lastChecked: new Date(), error: e?.message };
+  } finally {
+    clearTimeout(timer);
+  }
+}
+
+/**
+ * Pings MongoDB periodically, so health requests only read the last known state
+ */
+export function startMongodbHealthCheck() {
+  pingMongodb();
+  setInterval(pingMongodb, mongodbCheckIntervalMs).unref();
+}
+
+export function mongodbHealth(): MongodbHealth {
+  return mongodbState;
+}
+
+/**
+ * Derives overall status from subsystems states. "fail" - rotor can't process events, "warn" - rotor works,
+ * but some subsystem is degraded (stale configuration, cache unavailable)
+ */
+export function healthStatus(checks: {
+  kafka?: KafkaConsumerStatus;
+  mongodb: MongodbHealth;
+  stores: string[];
+  redis?: string;
+}): HealthStatus {
+  if (checks.kafka && ["crashed", "stopped", "disconnected"].includes(checks.kafka.state)) {
+    return "fail";
+  }
+  if (checks.mongodb.status === "error" || checks.stores.some(s => s === "failed" || s === "stopped")) {
+    return "fail";
+  }
+  if (checks.kafka && checks.kafka.state !== "running") {
+    return "warn";
+  }
+  if (checks.stores.some(s => s !== "ok") || (checks.redis && checks.redis !== "ready")) {
+    return "warn";
+  }
+  return "pass";
+}
@@ -1,9 +1,11 @@
 import { Reader, ReaderModel, City, Isp } from "@maxmind/geoip2-node";
+import { Reader as MmdbReader } from "mmdb-lib";
 import * as zlib from "zlib";
 import * as tar from "tar";
 import { Geo } from "@jitsu/protocols/analytics";
-import NodeCache from "node-cache";
-import { getLog, requireDefined } from "juava";
+import { LRUCache } from "lru-cache";
+import { getLog, parseNumber, requireDefined } from "juava";
+import Prometheus from "prom-client";
 import { S3Client, GetObjectCommand } from "@aws-sdk/client-s3";
 
 const log = getLog("maxmind");
@@ -24,7 +26,28 @@ const composeURL = (licenseKeyOrURL: string, edition: Edition) => {
   }
 };
 
-const geoIpCache = new NodeCache({ stdTTL: 60 * 5, checkperiod: 60, useClones: false });
+/**
+ * Creates cache of resolved IPs. When cache is full, least recently used entries are evicted.
+ * Entries expire after 5 minutes without hits. Cache is flushed when databases are updated
+ */
+export function createGeoCache(maxKeys: number): LRUCache<string, Geo> {
+  return new LRUCache<string, Geo>({ max: maxKeys, ttl: 1000 * 60 * 5, updateAgeOnGet: true });
+}
+
+const geoIpCache = createGeoCache(parseNumber(process.env.MAXMIND_CACHE_MAX_KEYS, 100000));
+
+const geoIpCacheHits = new Prometheus.Counter({
+  name: "rotor_geo_cache_hits",
+  help: "Geo lookups served from cache",
+});
+const geoIpCacheMisses = new Prometheus.Counter({
+  name: "rotor_geo_cache_misses",
+  help: "Geo lookups resolved from MaxMind databases",
+});
+
+function cacheGeo(ip: string, geo: Geo) {
+  geoIpCache.set(ip, geo);
+}
 
 export interface GeoResolver {
   resolve(ip: string): Promise<Geo>;
@@ -44,83 +67,102 @@ async function test() {
   log.atInfo().log(`IP 209.142.68.29:`, JSON.stringify(await maxMindClient.resolve("209.142.68.29"), null, 2));
 }
 
-export async function initMaxMindClient(opts: {
+type MaxMindOpts = {
   licenseKey?: string;
   url?: string;
   s3Bucket?: string;
-}): Promise<GeoResolver> {
-  const { licenseKey, s3Bucket, url } = opts;
-  if (!licenseKey && !url && !s3Bucket) {
-    log.atError().log("licenseKey, url or s3Bucket must be provided. GeoIP resolution will not work.");
-    return DummyResolver;
-  }
-  let loadFunc: LoadFunction;
-  let s3client: S3Client = undefined as any as S3Client;
-  if (s3Bucket) {
-    s3client = new S3Client({
-      region: requireDefined(process.env.S3_REGION, "S3_REGION is not provided"),
-      credentials: {
-        accessKeyId: requireDefined(process.env.S3_ACCESS_KEY_ID, "S3_ACCESS_KEY_ID is not provided"),
-        secretAccessKey: requireDefined(process.env.S3_SECRET_ACCESS_KEY, "S3_SECRET_ACCESS_KEY is not provided"),
-      },
-    });
-    loadFunc = (edition: Edition) => loadFromS3(s3client, s3Bucket, edition);
-  } else {
-    loadFunc = (edition: Edition) => loadFromURL(composeURL(licenseKey || url || "", edition));
-  }
+};
 
-  let cityReader: ReaderModel | undefined;
-  let countryReader: ReaderModel | undefined;
-  let ispReader: ReaderModel | undefined;
-  let asnReader: ReaderModel | undefined;
-  let domainReader: ReaderModel | undefined;
-  let connectionTypeReader: ReaderModel | undefined;
+type ReaderKey = "cityReader" | "countryReader" | "ispReader" | "asnReader" | "domainReader" | "connectionTypeReader";
 
-  const cityDb = await download(loadFunc, "GeoIP2-City");
-  if (cityDb.reader) {
-    cityReader = cityDb.reader;
-  }
-  const countryDb = await download(loadFunc, "GeoIP2-Country");
-  if (countryDb.reader) {
-    countryReader = countryDb.reader;
-  }
+type Database = {
+  //edition reader was loaded from. Paid editions may fall back to free analogs
+  edition: Edition;
+  //build date from database metadata
+  buildEpoch: Date;
+};
 
-  const ispDb = await download(loadFunc, "GeoIP2-ISP");
-  if (ispDb.reader) {
-    if (ispDb.edition === "GeoIP2-ISP") {
-      ispReader = ispDb.reader;
-    } else if (ispDb.edition === "GeoLite2-ASN") {
-      asnReader = ispDb.reader;
-    }
-  }
-  const domainDb = await download(loadFunc, "GeoIP2-Domain");
-  if (domainDb.reader) {
-    domainReader = domainDb.reader;
-  }
-  const contTypeDb = await download(loadFunc, "GeoIP2-Connection-Type");
-  if (contTypeDb.reader) {
-    connectionTypeReader = contTypeDb.reader;
-  }
+type Readers = Partial<Record<ReaderKey, ReaderModel>> & {
+  databases?: Partial<Record<ReaderKey, Database>>;
+};
+
+//interval to re-download databases. 0 - databases are loaded only on startup
+const updateIntervalHours = parseNumber(process.env.MAXMIND_UPDATE_INTERVAL_HOURS, 0);
+
+const maxmindBuildEpoch = new Prometheus.Gauge({
+  name: "rotor_maxmind_build_epoch",
+  help: "Build date of loaded MaxMind databases in unix seconds",
+  labelNames: ["edition"] as const,
+});
+let lastUpdate: Date | undefined;
+let buildDates: Record<string, Date> = {};
+
+/**
+ * Time of the last successful download of MaxMind databases
+ */
+export function maxMindLastUpdate(): Date | undefined {
+  return lastUpdate;
+}
 
-  if (s3client) {
-    s3client.destroy();
+/**
+ * Build dates of loaded MaxMind databases by edition
+ */
+export function maxMindBuildDates(): Record<string, Date> {
+  return buildDates;
+}
+
+function reportLoaded(readers: Readers) {
+  lastUpdate = new Date();
+  buildDates = {};
+  maxmindBuildEpoch.reset();
+  for (const db of Object.values(readers.databases || {})) {
+    buildDates[db.edition] = db.buildEpoch;
+    maxmindBuildEpoch.set({ edition: db.edition }, Math.floor(db.buildEpoch.getTime() / 1000));
   }
+}
 
-  if (!cityReader && !countryReader && !ispReader && !asnReader && !domainReader && !connectionTypeReader) {
+export async function initMaxMindClient(opts: MaxMindOpts): Promise<GeoResolver> {
+  const { licenseKey, s3Bucket, url } = opts;
+  if (!licenseKey && !url && !s3Bucket) {
+    log.atError().log("licenseKey, url or s3Bucket must be provided. GeoIP resolution will not work.");
+    return DummyResolver;
+  }
+  let readers = await loadReaders(opts);
+  if (!hasReaders(readers)) {
     log.atError().log("Failed to load MaxMind databases. GeoIP resolution will not work.");
     return DummyResolver;
   } else {
+    reportLoaded(readers);
+    if (updateIntervalHours > 0) {
+      setInterval(async () => {
+        try {
+          const newReaders = await loadReaders(opts);
+          if (hasReaders(newReaders)) {
+            readers = mergeReaders(readers, newReaders);
+            geoIpCache.clear();
+            reportLoaded(readers);
+            log.atInfo().log("MaxMind databases were updated");
+          } else {
+            log.atError().log("Failed to update MaxMind databases. Keeping previously loaded databases.");
+          }
+        } catch (e: any) {
+          log.atError().withCause(e).log("Failed to update MaxMind databases");
+        }
+      }, updateIntervalHours * 60 * 60 * 1000).unref();
+    }
     return {
       resolve: async (ip: string) => {
         try {
           if (!ip) {
             return {};
           }
+          const { cityReader, countryReader, ispReader, asnReader, domainReader, connectionTypeReader } = readers;
           const cached = geoIpCache.get(ip);
           if (cached) {
-            geoIpCache.ttl(ip);
-            return cached as Geo;
+            geoIpCacheHits.inc();
+            return cached;
           }
+          geoIpCacheMisses.inc();
           const geo = (
             cityReader ? cityReader.city(ip) : countryReader ? countryReader.country(ip) : undefined
           ) as City;
@@ -128,7 +170,7 @@ export async function initMaxMindClient(opts: {
           const domain = domainReader ? domainReader.domain(ip) : undefined;
           const connectionType = connectionTypeReader ? connectionTypeReader.connectionType(ip) : undefined;
           if (!geo && !isp && !domain && !connectionType) {
-            geoIpCache.set(ip, {});
+            cacheGeo(ip, {});
             return {};
           }
           let geoPart: Geo = geo
@@ -214,11 +256,11 @@ export async function initMaxMindClient(opts: {
             ...geoPart,
             ...ispPart,
           };
-          geoIpCache.set(ip, finalGeo);
+          cacheGeo(ip, finalGeo);
           return finalGeo;
         } catch (e: any) {
           log.atDebug().log(`Failed to resolve geo for ${ip}: ${e.message}`);
-          geoIpCache.set(ip, {});
+          cacheGeo(ip, {});
           return {};
         }
       },
@@ -226,6 +268,81 @@ export async function initMaxMindClient(opts: {
   }
 }
 
+function hasReaders(r: Readers): boolean {
+  return !!(r.cityReader || r.countryReader || r.ispReader || r.asnReader || r.domainReader || r.connectionTypeReader);
+}
+
+/**
+ * Replaces only databases that were successfully downloaded. For databases that failed to download (or fell back
+ * to a free analog of previously loaded paid edition) previously loaded readers are kept
+ */
+function mergeReaders(current: Readers, update: Readers): Readers {
+  const merged: Readers = { databases: {} };
+  const keys = Object.keys({ ...current, ...update }).filter(k => k !== "databases") as ReaderKey[];
+  for (const key of keys) {
+    const currentDb = current.databases?.[key];
+    const updateDb = update.databases?.[key];
+    const downgrade = !!currentDb?.edition.startsWith("GeoIP2") && !!updateDb?.edition.startsWith("GeoLite2");
+    if (update[key] && !downgrade) {
+      merged[key] = update[key];
+      merged.databases![key] = updateDb;
+    } else if (current[key]) {
+      log.atWarn().log(`Failed to update ${currentDb?.edition || key} database. Keeping previously loaded one`);
+      merged[key] = current[key];
+      merged.databases![key] = currentDb;
+    }
+  }
+  //asn is a free fallback of isp database. Once isp is loaded, asn is not needed
+  if (merged.ispReader) {
+    delete merged.asnReader;
+    delete merged.databases!.asnReader;
+  }
+  return merged;
+}
+
+async function loadReaders(opts: MaxMindOpts): Promise<Readers> {
+  const { licenseKey, s3Bucket, url } = opts;
+  let loadFunc: LoadFunction;
+  let s3client: S3Client = undefined as any as S3Client;
+  if (s3Bucket) {
+    s3client = new S3Client({
+      region: requireDefined(process.env.S3_REGION, "S3_REGION is not provided"),
+      credentials: {
+        accessKeyId: requireDefined(process.env.S3_ACCESS_KEY_ID, "S3_ACCESS_KEY_ID is not provided"),
+        secretAccessKey: requireDefined(process.env.S3_SECRET_ACCESS_KEY, "S3_SECRET_ACCESS_KEY is not provided"),
+      },
+    });
+    loadFunc = (edition: Edition) => loadFromS3(s3client, s3Bucket, edition);
+  } else {
+    loadFunc = (edition: Edition) => loadFromURL(composeURL(licenseKey || url || "", edition));
+  }
+  try {
+    const readers: Readers = { databases: {} };
+    const setReader = (key: ReaderKey, db: { reader?: ReaderModel; edition: Edition; buildEpoch?: Date }) => {
+      if (db.reader && db.buildEpoch) {
+        readers[key] = db.reader;
+        readers.databases![key] = { edition: db.edition, buildEpoch: db.buildEpoch };
+      }
+    };
+    setReader("cityReader", await download(loadFunc, "GeoIP2-City"));
+    setReader("countryReader", await download(loadFunc, "GeoIP2-Country"));
+
+    const ispDb = await download(loadFunc, "GeoIP2-ISP");
+    if (ispDb.edition === "GeoIP2-ISP") {
+      setReader("ispReader", ispDb);
+    } else if (ispDb.edition === "GeoLite2-ASN") {
+      setReader("asnReader", ispDb);
+    }
+    setReader("domainReader", await download(loadFunc, "GeoIP2-Domain"));
+    setReader("connectionTypeReader", await download(loadFunc, "GeoIP2-Connection-Type"));
+    return readers;
+  } finally {
+    if (s3client) {
+      s3client.destroy();
+    }
+  }
+}
+
 async function loadFromS3(client: S3Client, bucket: string, edition: Edition): Promise<Buffer> {
   try {
     const command = new GetObjectCommand({ Bucket: bucket, Key: edition + ".tar.gz" });
@@ -243,12 +360,12 @@ async function loadFromS3(client: S3Client, bucket: string, edition: Edition): P
 async function download(
   loadFunction: LoadFunction,
   edition: Edition
-): Promise<{ reader?: ReaderModel; edition: Edition }> {
+): Promise<{ reader?: ReaderModel; edition: Edition; buildEpoch?: Date }> {
   try {
     const b = await loadFunction(edition);
-    const reader = Reader.openBuffer(b);
-    log.atInfo().log(`Successfully downloaded ${edition} edition`);
-    return { reader, edition };
+    const db = openDatabase(b);
+    log.atInfo().log(`Successfully downloaded ${edition} edition built at ${db.buildEpoch.toISOString()}`);
+    return { ...db, edition };
   } catch (e: any) {
     const freeEdition = freeAnalog(edition as PaidEdition);
     if (!freeEdition) {
@@ -263,9 +380,9 @@ async function download(
       .log(`Failed to download ${edition} edition: ${e.message}. Trying to download free ${freeEdition} edition`);
     try {
       const b = await loadFunction(freeEdition);
-      const reader = Reader.openBuffer(b);
-      log.atInfo().log(`Successfully downloaded free ${freeEdition} edition`);
-      return { reader, edition: freeEdition };
+      const db = openDatabase(b);
+      log.atInfo().log(`Successfully downloaded free ${freeEdition} edition built at ${db.buildEpoch.toISOString()}`);
+      return { ...db, edition: freeEdition };
     } catch (e: any) {
       log.atError().log(`Failed to download ${freeEdition} edition: ${e.message}`);
       return { edition: freeEdition };
@@ -273,6 +390,11 @@ async function download(
   }
 }
 
+function openDatabase(b: Buffer): { reader: ReaderModel; buildEpoch: Date } {
+  //geoip2 reader doesn't expose database metadata, so build date is read with low-level mmdb reader
+  return { reader: Reader.openBuffer(b), buildEpoch: new MmdbReader(b).metadata.buildEpoch };
+}
+
 async function loadFromURL(url: string): Promise<Buffer> {
   const res = await fetch(url);
   if (res.ok) {
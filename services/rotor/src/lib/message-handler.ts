@@ -20,7 +20,8 @@ import {
 } from "@jitsu/core-functions";
 import NodeCache from "node-cache";
 import { buildFunctionChain, checkError, FuncChain, FuncChainFilter, runChain } from "./functions-chain";
-import { Redis } from "ioredis";
+import { RedisClient } from "./redis";
+import { ThrottledError } from "./rate-limiter";
 const log = getLog("rotor");
 
 const anonymousEventsStore = mongoAnonymousEventsStore();
@@ -37,7 +38,7 @@ export type MessageHandlerContext = {
   metrics?: Metrics;
   geoResolver?: GeoResolver;
   dummyPersistentStore?: TTLStore;
-  redisClient?: Redis;
+  redisClient?: RedisClient;
 };
 
 export function functionFilter(errorFunctionId?: string) {
@@ -136,6 +137,17 @@ export async function rotorMessageHandler(
     funcsChainCache.set(cacheKey, funcChain);
   }
 
+  //synchronous http requests (retriesEnabled=false) are not capped: they can be neither postponed nor held
+  //longer than caller's request timeout
+  if (funcChain.eventsLimiter && retriesEnabled) {
+    //don't occupy processing slot while waiting, postpone event via retry topic instead
+    if (!funcChain.eventsLimiter.tryAcquire()) {
+      throw new ThrottledError(
+        `Connection ${connection.id} exceeded limit of ${funcChain.eventsLimiter.perSecond} events per second`
+      );
+    }
+  }
+
   if (retries === 0) {
     await profilesHandler(rotorContext, ctx, connection, event);
   }
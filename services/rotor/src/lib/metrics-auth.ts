@@ -0,0 +1,29 @@
+import { checkHash, checkRawToken } from "juava";
+
+function parseTokens(tokens?: string): string[] {
+  return (tokens || "")
+    .split(",")
+    .map(t => t.trim())
+    .filter(t => !!t);
+}
+
+/**
+ * Checks Authorization header of metrics endpoint. Tokens are configured the same way as rotor's auth tokens:
+ * ROTOR_METRICS_AUTH_TOKENS - hashed tokens, ROTOR_METRICS_RAW_AUTH_TOKENS - plain tokens.
+ * Endpoint is open if neither is set
+ */
+export function checkMetricsAuth(authHeader: string): boolean {
+  const hashed = parseTokens(process.env.ROTOR_METRICS_AUTH_TOKENS);
+  const raw = parseTokens(process.env.ROTOR_METRICS_RAW_AUTH_TOKENS);
+  if (hashed.length === 0 && raw.length === 0) {
+    return true;
+  }
+  if (!authHeader.startsWith("Bearer ")) {
+    return false;
+  }
+  const token = authHeader.substring("Bearer ".length).trim();
+  if (!token) {
+    return false;
+  }
+  return hashed.some(t => checkHash(t, token)) || raw.some(t => checkRawToken(t, token));
+}
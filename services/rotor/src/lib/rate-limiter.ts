@@ -0,0 +1,82 @@
+import { FunctionChainContext } from "@jitsu/core-functions";
+
+export const ThrottledErrorName = "ThrottledError";
+
+/**
+ * Thrown when event exceeds connection's throughput cap. Such events are postponed via retry topic
+ */
+export class ThrottledError extends Error {
+  constructor(message: string) {
+    super(message);
+    this.name = ThrottledErrorName;
+  }
+}
+
+/**
+ * Token bucket rate limiter. Allows bursts up to perSecond operations.
+ */
+export type RateLimiter = {
+  perSecond: number;
+  //waits until the operation is allowed
+  acquire: () => Promise<void>;
+  //returns false if the operation exceeds the limit
+  tryAcquire: () => boolean;
+};
+
+export function createRateLimiter(perSecond: number): RateLimiter {
+  const capacity = Math.max(1, perSecond);
+  let tokens = capacity;
+  let lastRefill = Date.now();
+  const tryAcquire = () => {
+    const now = Date.now();
+    tokens = Math.min(capacity, tokens + ((now - lastRefill) * perSecond) / 1000);
+    lastRefill = now;
+    if (tokens >= 1) {
+      tokens -= 1;
+      return true;
+    }
+    return false;
+  };
+  return {
+    perSecond,
+    tryAcquire,
+    acquire: async () => {
+      while (!tryAcquire()) {
+        await new Promise(resolve => setTimeout(resolve, Math.ceil(((1 - tokens) * 1000) / perSecond)));
+      }
+    },
+  };
+}
+
+//limiters must outlive function chains (which are rebuilt every minute), otherwise each rebuild resets the limit
+const limiters = new Map<string, RateLimiter>();
+
+/**
+ * Returns long-lived limiter for the key or undefined if limit is not set.
+ * Limiter is recreated only when the limit changes.
+ */
+export function getRateLimiter(key: string, perSecond?: number): RateLimiter | undefined {
+  if (!perSecond || perSecond <= 0) {
+    limiters.delete(key);
+    return undefined;
+  }
+  let limiter = limiters.get(key);
+  if (!limiter || limiter.perSecond !== perSecond) {
+    limiter = createRateLimiter(perSecond);
+    limiters.set(key, limiter);
+  }
+  return limiter;
+}
+
+export function rateLimitedFetch(
+  fetch: FunctionChainContext["fetch"],
+  limiter?: RateLimiter
+): FunctionChainContext["fetch"] {
+  if (!limiter) {
+    return fetch;
+  }
+  return async (url, opts, extra) => {
+    await limiter.acquire();
+    return fetch(url, opts, extra);
+  };
+}
@@ -0,0 +1,44 @@
+import { AnonymousEventsStore } from "@jitsu/protocols/functions";
+import Prometheus from "prom-client";
+
+const anonymousEventsStored = new Prometheus.Counter({
+  name: "rotor_recognition_anonymous_events_stored",
+  help: "Anonymous events stored for user recognition",
+  // add `as const` here to enforce label names
+  labelNames: ["connectionId"] as const,
+});
+const usersIdentified = new Prometheus.Counter({
+  name: "rotor_recognition_users_identified",
+  help: "Identified users that had pending anonymous events",
+  labelNames: ["connectionId"] as const,
+});
+const eventsUpdated = new Prometheus.Counter({
+  name: "rotor_recognition_events_updated",
+  help: "Anonymous events re-sent to destination with identified fields",
+  labelNames: ["connectionId"] as const,
+});
+const evictTime = new Prometheus.Histogram({
+  name: "rotor_recognition_evict_time",
+  help: "Time to load and delete pending anonymous events in ms",
+  buckets: [1, 10, 50, 100, 200, 500, 1000, 2000, 5000],
+  labelNames: ["connectionId"] as const,
+});
+
+export function withRecognitionMetrics(store: AnonymousEventsStore, connectionId: string): AnonymousEventsStore {
+  return {
+    async addEvent(collectionName, anonymousId, event, ttlDays) {
+      await store.addEvent(collectionName, anonymousId, event, ttlDays);
+      anonymousEventsStored.inc({ connectionId });
+    },
+    async evictEvents(collectionName, anonymousId) {
+      const sw = Date.now();
+      const res = await store.evictEvents(collectionName, anonymousId);
+      evictTime.observe({ connectionId }, Date.now() - sw);
+      if (res.length > 0) {
+        usersIdentified.inc({ connectionId });
+        eventsUpdated.inc({ connectionId }, res.length);
+      }
+      return res;
+    },
+  };
+}
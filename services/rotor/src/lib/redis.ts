@@ -1,5 +1,5 @@
-import { Redis } from "ioredis";
-import { requireDefined, getLog } from "juava";
+import { Cluster, Redis } from "ioredis";
+import { requireDefined, getLog, isTruish } from "juava";
 
 const log = getLog("redis");
 
@@ -18,29 +18,96 @@ function hideSensitiveInfoFromURL(url: string) {
   return parsed.toString();
 }
 
-export function createRedis(): Redis {
-  const redisUrl = requireDefined(process.env.REDIS_URL, "env REDIS_URL is not defined");
-  log.atDebug().log(`Building redis client for ${hideSensitiveInfoFromURL(redisUrl)}`);
-  let tls: any = undefined;
-  if (redisUrl.startsWith("rediss://")) {
-    tls = {
-      rejectUnauthorized: false,
-    };
+export type RedisClient = Redis | Cluster;
+
+function parseNodes(nodes: string): { host: string; port: number }[] {
+  return nodes
+    .split(",")
+    .map(n => n.trim())
+    .filter(n => !!n)
+    .map(n => {
+      const [host, port] = n.split(":");
+      return { host, port: port ? parseInt(port) : 6379 };
+    });
+}
+
+export function isRedisConfigured(): boolean {
+  return !!(process.env.REDIS_URL || process.env.REDIS_SENTINELS || process.env.REDIS_CLUSTER_NODES);
+}
+
+/**
+ * Creates redis client. Topology is selected by env:
+ * REDIS_CLUSTER_NODES - comma separated host:port list of cluster nodes,
+ * REDIS_SENTINELS - comma separated host:port list of sentinels with master name in REDIS_SENTINEL_MASTER,
+ * REDIS_URL - single redis instance.
+ * For cluster and sentinel modes credentials are set with REDIS_USERNAME, REDIS_PASSWORD and TLS with REDIS_TLS=true
+ */
+export function createRedis(): RedisClient {
+  let redisClient: RedisClient;
+  let description: string;
+  if (process.env.REDIS_CLUSTER_NODES) {
+    const nodes = parseNodes(process.env.REDIS_CLUSTER_NODES);
+    description = `cluster ${nodes.map(n => `${n.host}:${n.port}`).join(",")}`;
+    log.atDebug().log(`Building redis client for ${description}`);
+    redisClient = new Cluster(nodes, {
+      enableAutoPipelining: true,
+      redisOptions: {
+        ...topologyOptions(),
+        maxRetriesPerRequest: 3,
+      },
+    });
+  } else if (process.env.REDIS_SENTINELS) {
+    const sentinels = parseNodes(process.env.REDIS_SENTINELS);
+    const name = process.env.REDIS_SENTINEL_MASTER || "mymaster";
+    description = `sentinel ${name}@${sentinels.map(n => `${n.host}:${n.port}`).join(",")}`;
+    log.atDebug().log(`Building redis client for ${description}`);
+    redisClient = new Redis({
+      ...topologyOptions(),
+      sentinels,
+      name,
+      sentinelPassword: process.env.REDIS_SENTINEL_PASSWORD,
+      //without these options ioredis ignores tls in sentinel mode: sentinelTLS is used for connections to sentinels,
+      //enableTLSForSentinelMode - for connections to master
+      sentinelTLS: redisTls(),
+      enableTLSForSentinelMode: !!redisTls(),
+      maxRetriesPerRequest: 3,
+      lazyConnect: false,
+      enableAutoPipelining: true,
+    });
+  } else {
+    const redisUrl = requireDefined(process.env.REDIS_URL, "env REDIS_URL is not defined");
+    description = hideSensitiveInfoFromURL(redisUrl);
+    log.atDebug().log(`Building redis client for ${description}`);
+    let tls: any = undefined;
+    if (redisUrl.startsWith("rediss://")) {
+      tls = {
+        rejectUnauthorized: false,
+      };
+    }
+    redisClient = new Redis(redisUrl, {
+      maxRetriesPerRequest: 3,
+      tls: tls,
+      lazyConnect: false,
+      enableAutoPipelining: true,
+    });
   }
-  const redisClient = new Redis(requireDefined(process.env.REDIS_URL, "env REDIS_URL is not defined"), {
-    maxRetriesPerRequest: 3,
-    tls: tls,
-    lazyConnect: false,
-    enableAutoPipelining: true,
-  });
   redisClient.on("error", err => {
-    log
-      .atWarn()
-      .withCause(err)
-      .log(`Redis @ ${hideSensitiveInfoFromURL(redisUrl)} - failed to connect`);
+    log.atWarn().withCause(err).log(`Redis @ ${description} - failed to connect`);
   });
   redisClient.on("connect", () => {
-    log.atInfo().log(`Redis @ ${hideSensitiveInfoFromURL(redisUrl)} - successfully connected`);
+    log.atInfo().log(`Redis @ ${description} - successfully connected`);
   });
   return redisClient;
 }
+
+function redisTls() {
+  return isTruish(process.env.REDIS_TLS) ? { rejectUnauthorized: false } : undefined;
+}
+
+function topologyOptions() {
+  return {
+    username: process.env.REDIS_USERNAME,
+    password: process.env.REDIS_PASSWORD,
+    tls: redisTls(),
+  };
+}
@@ -2,6 +2,7 @@ import dayjs from "dayjs";
 import utc from "dayjs/plugin/utc";
 dayjs.extend(utc);
 import { RetryErrorName, DropRetryErrorName } from "@jitsu/functions-lib";
+import { ConnectionRetryLimits } from "@jitsu/core-functions";
 
 const MESSAGES_RETRY_COUNT = process.env.MESSAGES_RETRY_COUNT ? parseInt(process.env.MESSAGES_RETRY_COUNT) : 3;
 // MESSAGES_RETRY_BACKOFF_BASE defines base for exponential backoff in minutes.
@@ -19,24 +20,51 @@ export type retryPolicy = {
   delays: number[];
 };
 
-const retryDefaultDelays = (() => {
+/**
+ * Retry settings that can be overridden in connection options
+ */
+export type connectionRetryOptions = {
+  retries?: number;
+  retryBackoffBase?: number;
+};
+
+function backoffDelays(retries: number, base: number): number[] {
   const delays: number[] = [];
-  for (let i = 0; i < MESSAGES_RETRY_COUNT; i++) {
-    delays.push(Math.min(Math.pow(MESSAGES_RETRY_BACKOFF_BASE, i + 1), MESSAGES_RETRY_BACKOFF_MAX_DELAY));
+  for (let i = 0; i < retries; i++) {
+    delays.push(Math.min(Math.pow(base, i + 1), MESSAGES_RETRY_BACKOFF_MAX_DELAY));
   }
   return delays;
-})();
+}
+
+const retryDefaultDelays = backoffDelays(MESSAGES_RETRY_COUNT, MESSAGES_RETRY_BACKOFF_BASE);
 
 export const retryDefaultPolicy: retryPolicy = {
   retries: MESSAGES_RETRY_COUNT,
   delays: retryDefaultDelays,
 };
 
-export function getRetryPolicy(e: Error & { retryPolicy?: retryPolicy }): retryPolicy {
-  let retryPolicy = retryDefaultPolicy;
+export function connectionRetryPolicy(opts?: connectionRetryOptions): retryPolicy {
+  if (typeof opts?.retries === "undefined" && typeof opts?.retryBackoffBase === "undefined") {
+    return retryDefaultPolicy;
+  }
+  const retries = Math.max(0, Math.min(ConnectionRetryLimits.maxRetries, opts?.retries ?? MESSAGES_RETRY_COUNT));
+  const base = Math.min(ConnectionRetryLimits.maxBackoffBase, opts?.retryBackoffBase || MESSAGES_RETRY_BACKOFF_BASE);
+  return {
+    retries,
+    delays: backoffDelays(retries, base),
+  };
+}
+
+export function getRetryPolicy(
+  e: Error & { retryPolicy?: retryPolicy },
+  connectionOptions?: connectionRetryOptions
+): retryPolicy {
+  let retryPolicy = connectionRetryPolicy(connectionOptions);
   if (e.retryPolicy) {
+    // policy provided by function can only make retries more strict than connection policy
+    const maxRetries = retryPolicy.retries;
     retryPolicy = { ...retryPolicy, ...e.retryPolicy };
-    retryPolicy.retries = Math.min(MESSAGES_RETRY_COUNT, retryPolicy.retries);
+    retryPolicy.retries = Math.min(maxRetries, retryPolicy.retries);
     retryPolicy.delays = retryPolicy.delays.map(d => Math.min(MESSAGES_RETRY_BACKOFF_MAX_DELAY, d));
   }
   return retryPolicy;
@@ -58,9 +86,13 @@ export function retryLogMessage(retryPolicy: retryPolicy, retries: number): stri
   }`;
 }
 
-export function retryObject(e: Error & { retryPolicy?: retryPolicy }, retries: number) {
+export function retryObject(
+  e: Error & { retryPolicy?: retryPolicy },
+  retries: number,
+  connectionOptions?: connectionRetryOptions
+) {
   if (e.name === DropRetryErrorName || e.name === RetryErrorName) {
-    const retryPolicy = getRetryPolicy(e);
+    const retryPolicy = getRetryPolicy(e, connectionOptions);
     const retryTime = retryBackOffTime(retryPolicy, retries + 1);
     return { retry: { left: retryPolicy.retries - retries, ...(retryTime ? { time: retryTime } : {}) } };
   } else {
@@ -68,9 +100,13 @@ export function retryObject(e: Error & { retryPolicy?: retryPolicy }, retries: n
   }
 }
 
-export function retryLogMessageIfNeeded(e: Error & { retryPolicy?: retryPolicy }, retries: number) {
+export function retryLogMessageIfNeeded(
+  e: Error & { retryPolicy?: retryPolicy },
+  retries: number,
+  connectionOptions?: connectionRetryOptions
+) {
   if (e.name === DropRetryErrorName || e.name === RetryErrorName) {
-    const retryPolicy = getRetryPolicy(e);
+    const retryPolicy = getRetryPolicy(e, connectionOptions);
     return retryLogMessage(retryPolicy, retries);
   }
 }
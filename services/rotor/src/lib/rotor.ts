@@ -12,6 +12,7 @@ import type { Admin, Consumer, Producer, KafkaMessage } from "kafkajs";
 import { CompressionTypes } from "kafkajs";
 import { functionFilter, MessageHandlerContext } from "./message-handler";
 import { connectionsStore, functionsStore, workspaceStore } from "./repositories";
+import { ThrottledErrorName } from "./rate-limiter";
 
 const log = getLog("kafka-rotor");
 
@@ -24,6 +25,11 @@ export const CONNECTION_IDS_HEADER = "connection_ids";
 
 const concurrency = parseNumber(process.env.CONCURRENCY, 10);
 const fetchTimeoutMs = parseNumber(process.env.FETCH_TIMEOUT_MS, 2000);
+// delay before throttled message is returned for processing
+const throttledRetryDelaySec = parseNumber(process.env.THROTTLED_RETRY_DELAY_SEC, 10);
+// max time to wait for queued messages to be processed on shutdown. Unprocessed messages are put to retry topic.
+// Together with SHUTDOWN_EXTRA_DELAY_SEC it must fit into k8s termination grace period (30s by default)
+const drainTimeoutMs = 1000 * parseNumber(process.env.SHUTDOWN_DRAIN_TIMEOUT_SEC, 20);
 
 export type KafkaRotorConfig = {
   credentials: KafkaCredentials;
@@ -42,9 +48,18 @@ export type KafkaRotorConfig = {
   ) => Promise<FuncChainResult | undefined>;
 };
 
+export type KafkaConsumerStatus = {
+  state: "connecting" | "connected" | "running" | "disconnected" | "stopped" | "crashed";
+  lastHeartbeat?: Date;
+  error?: string;
+};
+
 export type KafkaRotor = {
   start: () => Promise<Metrics>;
   close: () => Promise<void>;
+  consumerStatus: () => KafkaConsumerStatus;
+  //time of the last successfully processed message per connection id. Contains connection ids, don't expose publicly
+  lastProcessed: () => Record<string, Date>;
 };
 
 export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
@@ -55,6 +70,20 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
   let closeQueue: () => Promise<void>;
   let interval: any;
   let metrics: Metrics;
+  const consumerStatus: KafkaConsumerStatus = { state: "connecting" };
+  const lastProcessed = new Map<string, Date>();
+  //forget connections that were deleted, so the map doesn't grow forever
+  const pruneLastProcessed = () => {
+    const store = connectionsStore.getCurrent();
+    if (!store?.enabled) {
+      return;
+    }
+    for (const connectionId of lastProcessed.keys()) {
+      if (!store.getObject(connectionId)) {
+        lastProcessed.delete(connectionId);
+      }
+    }
+  };
   return {
     start: async () => {
       const kafka = connectToKafka({ defaultAppId: kafkaClientId, ...cfg.credentials });
@@ -63,6 +92,26 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
         allowAutoTopicCreation: false,
         sessionTimeout: 10000,
       });
+      consumer.on(consumer.events.CONNECT, () => {
+        consumerStatus.state = "connected";
+      });
+      consumer.on(consumer.events.GROUP_JOIN, () => {
+        consumerStatus.state = "running";
+        consumerStatus.error = undefined;
+      });
+      consumer.on(consumer.events.HEARTBEAT, e => {
+        consumerStatus.lastHeartbeat = new Date(e.timestamp);
+      });
+      consumer.on(consumer.events.DISCONNECT, () => {
+        consumerStatus.state = "disconnected";
+      });
+      consumer.on(consumer.events.STOP, () => {
+        consumerStatus.state = "stopped";
+      });
+      consumer.on(consumer.events.CRASH, e => {
+        consumerStatus.state = "crashed";
+        consumerStatus.error = e.payload.error?.message;
+      });
       await consumer.connect();
       log.atInfo().log("Subscribing to kafka topics: ", kafkaTopics);
       await consumer.subscribe({ topics: kafkaTopics, fromBeginning: true });
@@ -102,7 +151,26 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
         // add `as const` here to enforce label names
         labelNames: ["topic"] as const,
       });
+      const destinationRetries = new Prometheus.Counter({
+        name: "rotor_destination_retries",
+        help: "messages scheduled for retry per destination",
+        // add `as const` here to enforce label names
+        labelNames: ["destinationId"] as const,
+      });
+      const destinationDeadLettered = new Prometheus.Counter({
+        name: "rotor_destination_dead_lettered",
+        help: "messages dead lettered per destination after retries exhaustion",
+        // add `as const` here to enforce label names
+        labelNames: ["destinationId"] as const,
+      });
+      const messagesThrottled = new Prometheus.Counter({
+        name: "rotor_destination_throttled",
+        help: "messages postponed because connection exceeded events per second limit",
+        // add `as const` here to enforce label names
+        labelNames: ["destinationId"] as const,
+      });
       interval = setInterval(async () => {
+        pruneLastProcessed();
         try {
           for (const topic of kafkaTopics) {
             const watermarks = await admin.fetchTopicOffsets(topic);
@@ -131,8 +199,7 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
         const headers = message.headers || {};
         const retries = headers[RETRY_COUNT_HEADER] ? parseInt(headers[RETRY_COUNT_HEADER].toString()) : 0;
         const retriedFunctionId = headers[FUNCTION_ID_HEADER] ? headers[FUNCTION_ID_HEADER].toString() : "";
-        const connectionIds =
-          headers && headers[CONNECTION_IDS_HEADER] ? headers[CONNECTION_IDS_HEADER].toString().split(",") : [""];
+        const connectionIds = messageConnectionIds(message);
         const conProms = connectionIds.map(connectionId =>
           handle(
             value.toString(),
@@ -152,25 +219,48 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
             retries,
             fetchTimeoutMs
           )
-            .then(() => messagesProcessed.inc({ topic, partition }))
+            .then(() => {
+              messagesProcessed.inc({ topic, partition });
+              if (connectionId) {
+                lastProcessed.set(connectionId, new Date());
+              }
+            })
             .catch(async e => {
-              const retryPolicy = getRetryPolicy(e);
-              const retryTime = retryBackOffTime(retryPolicy, retries + 1);
+              const connection = connectionsStore.getCurrent()?.getObject(connectionId);
+              const destinationId = connection?.destinationId ?? "unknown";
               const newMessage = e.event
                 ? JSON.stringify({ ...JSON.parse(value.toString()), httpPayload: e.event })
                 : value;
-              log
-                .atError()
-                .withCause(e)
-                .log(
-                  `Failed to process function ${e.functionId} for connection ${connectionId} messageId: ${
-                    message.key || "(no key set)"
-                  }. ${retryLogMessage(retryPolicy, retries)}`
-                );
-              if (!retryTime) {
-                messagesDeadLettered.inc({ topic });
+              let retryTime: string;
+              let retriesHeader = retries;
+              let functionId = e.functionId;
+              if (e.name === ThrottledErrorName) {
+                // event is throttled before functions run: keep the set of functions the message was retried for
+                functionId = retriedFunctionId;
+                log.atDebug().log(`${e.message}. Message ID: ${message.key || "(no key set)"} postponed`);
+                messagesThrottled.inc({ destinationId });
+                retryTime = dayjs().add(throttledRetryDelaySec, "second").utc().toISOString();
+                // retry consumer increments retries count when message is returned to the original topic.
+                // Throttled message must not spend retry attempt
+                retriesHeader = retries - 1;
               } else {
-                messagesRequeued.inc({ topic });
+                const retryPolicy = getRetryPolicy(e, connection?.options);
+                retryTime = retryBackOffTime(retryPolicy, retries + 1);
+                log
+                  .atError()
+                  .withCause(e)
+                  .log(
+                    `Failed to process function ${e.functionId} for connection ${connectionId} messageId: ${
+                      message.key || "(no key set)"
+                    }. ${retryLogMessage(retryPolicy, retries)}`
+                  );
+                if (!retryTime) {
+                  messagesDeadLettered.inc({ topic });
+                  destinationDeadLettered.inc({ destinationId });
+                } else {
+                  messagesRequeued.inc({ topic });
+                  destinationRetries.inc({ destinationId });
+                }
               }
               const requeueTopic = retryTime ? retryTopic() : deatLetterTopic();
               try {
@@ -181,27 +271,75 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
                     {
                       value: newMessage,
                       // on first retry we create a new key so if more than one destination fails - they will be retried independently
-                      key: retries === 0 ? `${message.key}_${connectionId}` : message.key,
+                      key:
+                        retries === 0 && !`${message.key}`.endsWith(`_${connectionId}`)
+                          ? `${message.key}_${connectionId}`
+                          : message.key,
                       headers: {
                         [ERROR_HEADER]: e.message?.substring(0, 1024) || "unknown error",
-                        [RETRY_COUNT_HEADER]: `${retries}`,
+                        [RETRY_COUNT_HEADER]: `${retriesHeader}`,
                         [ORIGINAL_TOPIC_HEADER]: topic,
                         [RETRY_TIME_HEADER]: retryTime,
                         [CONNECTION_IDS_HEADER]: connectionId,
-                        ...(e.functionId ? { [FUNCTION_ID_HEADER]: e.functionId } : {}),
+                        ...(functionId ? { [FUNCTION_ID_HEADER]: functionId } : {}),
                       },
                     },
                   ],
                 });
               } catch (e) {
-                log.atDebug().withCause(e).log(`Failed to put message to ${topic}: ${message.value}`);
+                log
+                  .atError()
+                  .withCause(e)
+                  .log(`Failed to put message ${message.key || "(no key set)"} to ${requeueTopic}: ${message.value}`);
               }
             })
+            .finally(() => inFlight.get(message)?.pending.delete(connectionId))
         );
         await Promise.all(conProms);
       }
 
       const queue = new PQueue({ concurrency });
+      // messages that are queued or being processed with connections that are not finished yet.
+      // Their offsets may be already committed
+      const inFlight = new Map<KafkaMessage, { topic: string; pending: Set<string> }>();
+
+      const requeueUnprocessed = async () => {
+        const unprocessed = [...inFlight.entries()].filter(([, { pending }]) => pending.size > 0);
+        if (unprocessed.length === 0) {
+          return;
+        }
+        const now = dayjs().utc().toISOString();
+        try {
+          await producer.send({
+            topic: retryTopic(),
+            compression: getCompressionType(),
+            messages: unprocessed.map(([message, { topic, pending }]) => {
+              const headers = message.headers || {};
+              const retries = headers[RETRY_COUNT_HEADER] ? parseInt(headers[RETRY_COUNT_HEADER].toString()) : 0;
+              const connectionIds = [...pending].filter(id => !!id);
+              return {
+                value: message.value,
+                key: message.key,
+                headers: {
+                  ...headers,
+                  // only connections that didn't finish. Others are either processed or already put to retry topic
+                  ...(connectionIds.length > 0 ? { [CONNECTION_IDS_HEADER]: connectionIds.join(",") } : {}),
+                  // retry consumer increments retries count. Unprocessed message must not spend retry attempt
+                  [RETRY_COUNT_HEADER]: `${retries - 1}`,
+                  [ORIGINAL_TOPIC_HEADER]: topic,
+                  [RETRY_TIME_HEADER]: now,
+                },
+              };
+            }),
+          });
+          log.atInfo().log(`${unprocessed.length} unprocessed messages were put to ${retryTopic()}`);
+        } catch (e) {
+          log
+            .atError()
+            .withCause(e)
+            .log(`Failed to put ${unprocessed.length} unprocessed messages to ${retryTopic()}. They will be lost`);
+        }
+      };
 
       const onSizeLessThan = async (limit: number) => {
         // Instantly resolve if the queue is empty.
@@ -221,8 +359,27 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
         });
       };
       closeQueue = async () => {
-        log.atInfo().log("Closing queue...");
-        await queue.onIdle();
+        log.atInfo().log(`Closing queue... Queued: ${queue.size} running: ${queue.pending}`);
+        let timer: any;
+        const drained = await Promise.race([
+          queue.onIdle().then(() => true),
+          new Promise<boolean>(resolve => {
+            timer = setTimeout(() => resolve(false), drainTimeoutMs);
+          }),
+        ]);
+        clearTimeout(timer);
+        if (!drained) {
+          log
+            .atWarn()
+            .log(
+              `Queue wasn't drained in ${drainTimeoutMs / 1000}s. Abandoning ${queue.size} queued and ${
+                queue.pending
+              } running messages`
+            );
+          queue.clear();
+          // messages that are still running may be processed twice: here and after retry
+          await requeueUnprocessed();
+        }
       };
 
       await consumer.run({
@@ -232,7 +389,14 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
         eachMessage: async ({ message, topic, partition }) => {
           //make sure that queue has no more entities than concurrency limit (running tasks not included)
           await onSizeLessThan(concurrency);
-          queue.add(async () => onMessage(message, topic, partition));
+          inFlight.set(message, { topic, pending: new Set(messageConnectionIds(message)) });
+          queue.add(async () => {
+            try {
+              await onMessage(message, topic, partition);
+            } finally {
+              inFlight.delete(message);
+            }
+          });
         },
       });
 
@@ -252,9 +416,19 @@ export function kafkaRotor(cfg: KafkaRotorConfig): KafkaRotor {
       }
       log.atInfo().log("Kafka-rotor closed gracefully. 💜");
     },
+    consumerStatus: () => ({ ...consumerStatus }),
+    lastProcessed: () => {
+      pruneLastProcessed();
+      return Object.fromEntries(lastProcessed);
+    },
   };
 }
 
+function messageConnectionIds(message: KafkaMessage): string[] {
+  const headers = message.headers || {};
+  return headers[CONNECTION_IDS_HEADER] ? headers[CONNECTION_IDS_HEADER].toString().split(",") : [""];
+}
+
 export function getCompressionType() {
   switch (process.env.KAFKA_TOPIC_COMPRESSION) {
     case "gzip":
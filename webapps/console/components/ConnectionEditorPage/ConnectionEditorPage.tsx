@@ -8,6 +8,7 @@ import { useRouter } from "next/router";
 import { assertTrue, getLog, requireDefined } from "juava";
 import { Button, Input, InputNumber, Radio, Switch, Tooltip } from "antd";
 import { BaseBulkerConnectionOptions, getCoreDestinationType } from "../../lib/schema/destinations";
+import { ConnectionRetryLimits } from "@jitsu/core-functions/src/meta";
 import { confirmOp, feedbackError, feedbackSuccess } from "../../lib/ui";
 import FieldListEditorLayout, { EditorItem } from "../FieldListEditorLayout/FieldListEditorLayout";
 import { DataLayoutType } from "@jitsu/protocols/analytics";
@@ -568,6 +569,30 @@ function ConnectionEditor({
       ),
     });
   }
+  if (hasZodFields(connectionOptionsZodType, "columnTypes")) {
+    configItems.push({
+      group: "Advanced",
+      name: "Column Types",
+      documentation: (
+        <>
+          SQL types of columns that override automatic type detection. Format is <code>column=type</code> separated by
+          new line, e.g. <code>revenue=numeric(38,9)</code>. Column names should match names in the destination table.
+          With multi-table data layouts the type is applied to the column in every table. Use{" "}
+          <code>table.column=type</code> to limit it to one table. Types are applied only when column is created.
+        </>
+      ),
+      component: (
+        <TextEditor
+          className="max-w-xs"
+          rows={3}
+          value={connectionOptions.columnTypes}
+          onChange={columnTypes => {
+            updateOptions({ columnTypes });
+          }}
+        />
+      ),
+    });
+  }
   if (hasZodFields(connectionOptionsZodType, "clickhouseSettings") && destinationType.id === "clickhouse") {
     configItems.push({
       group: "Advanced",
@@ -645,6 +670,120 @@ function ConnectionEditor({
       ),
     });
   }
+  if (hasZodFields(connectionOptionsZodType, "retries")) {
+    configItems.push({
+      group: "Advanced",
+      documentation: (
+        <>
+          Number of attempts to re-send events that failed with a retryable error. After all attempts are exhausted the
+          event is put to the dead-letter queue. Leave empty to use default settings.
+        </>
+      ),
+      name: "Retry Attempts",
+      component: (
+        <InputNumber
+          value={connectionOptions.retries}
+          size="small"
+          className="w-36"
+          min={0}
+          max={ConnectionRetryLimits.maxRetries}
+          onChange={retries => updateOptions({ retries: retries ?? undefined })}
+        />
+      ),
+    });
+  }
+  if (hasZodFields(connectionOptionsZodType, "retryBackoffBase")) {
+    configItems.push({
+      group: "Advanced",
+      documentation: (
+        <>
+          Base of exponential backoff in minutes. For example, with base <code>5</code> retries will be scheduled after
+          5, 25, 125 minutes. Leave empty to use default settings.
+        </>
+      ),
+      name: "Retry Backoff Base",
+      component: (
+        <InputNumber
+          value={connectionOptions.retryBackoffBase}
+          size="small"
+          className="w-36"
+          min={1}
+          max={ConnectionRetryLimits.maxBackoffBase}
+          onChange={retryBackoffBase => updateOptions({ retryBackoffBase: retryBackoffBase ?? undefined })}
+        />
+      ),
+    });
+  }
+  if (hasZodFields(connectionOptionsZodType, "maxEventsPerSecond")) {
+    configItems.push({
+      group: "Advanced",
+      documentation: (
+        <>
+          Maximum number of events per second this connection sends to the destination. Events exceeding the limit
+          are queued and delivered later rather than failed. The limit applies to each processing instance separately
+          and doesn't include other connections to the same destination. Leave empty for no limit.
+        </>
+      ),
+      name: "Max Events per Second",
+      component: (
+        <InputNumber
+          value={connectionOptions.maxEventsPerSecond}
+          size="small"
+          className="w-36"
+          min={1}
+          onChange={maxEventsPerSecond => updateOptions({ maxEventsPerSecond: maxEventsPerSecond ?? undefined })}
+        />
+      ),
+    });
+  }
+  if (hasZodFields(connectionOptionsZodType, "maxRequestsPerSecond")) {
+    configItems.push({
+      group: "Advanced",
+      documentation: (
+        <>
+          Maximum number of HTTP requests per second this connection makes to the destination API. Useful for APIs
+          that throttle aggressively. The limit applies to each processing instance separately and doesn't include
+          other connections to the same destination. Leave empty for no limit.
+        </>
+      ),
+      name: "Max Requests per Second",
+      component: (
+        <InputNumber
+          value={connectionOptions.maxRequestsPerSecond}
+          size="small"
+          className="w-36"
+          min={1}
+          onChange={maxRequestsPerSecond => updateOptions({ maxRequestsPerSecond: maxRequestsPerSecond ?? undefined })}
+        />
+      ),
+    });
+  }
+  if (hasZodFields(connectionOptionsZodType, "fetchLogLevel")) {
+    configItems.push({
+      group: "Advanced",
+      documentation: (
+        <>
+          Which HTTP requests to the destination are written to the connection's log. <b>All</b> - every request,{" "}
+          <b>Errors only</b> - failed requests and non-2xx responses, <b>None</b> - requests are logged only when
+          function debug mode is on.
+        </>
+      ),
+      name: "HTTP Requests Log",
+      component: (
+        <Radio.Group
+          size="small"
+          optionType="button"
+          value={connectionOptions.fetchLogLevel || "info"}
+          options={[
+            { label: "All", value: "info" },
+            { label: "Errors only", value: "error" },
+            { label: "None", value: "debug" },
+          ]}
+          onChange={e => updateOptions({ fetchLogLevel: e.target.value === "info" ? undefined : e.target.value })}
+        />
+      ),
+    });
+  }
   // if (hasZodFields(connectionOptionsZodType, "multithreading")) {
   //   configItems.push({
   //     group: "Advanced",
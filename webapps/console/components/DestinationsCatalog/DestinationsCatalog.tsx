@@ -13,7 +13,7 @@ import { useRouter } from "next/router";
 function groupDestinationTypes(): Record<string, DestinationType[]> {
   const groups: Record<string, DestinationType[]> = {};
 
-  const sortOrder = ["Datawarehouse", "Product Analytics", "CRM", "Block Storage"];
+  const sortOrder = ["Datawarehouse", "Product Analytics", "CRM", "Messaging", "Block Storage"];
 
   coreDestinations.forEach(d => {
     if (d.tags) {
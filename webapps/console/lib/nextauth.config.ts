@@ -1,6 +1,7 @@
 import GithubProvider from "next-auth/providers/github";
 import CredentialsProvider from "next-auth/providers/credentials";
 import { NextAuthOptions, User } from "next-auth";
+import { OAuthConfig } from "next-auth/providers/oauth";
 import { db } from "./server/db";
 import { checkHash, createHash, hash, requireDefined } from "juava";
 import { ApiError } from "./shared/errors";
@@ -15,6 +16,7 @@ const crypto = require("crypto");
 const log = getServerLog("auth");
 
 export const githubLoginEnabled = !!process.env.GITHUB_CLIENT_ID;
+export const oidcLoginEnabled = !!process.env.AUTH_OIDC_ISSUER;
 export const credentialsLoginEnabled =
   isTruish(process.env.ENABLE_CREDENTIALS_LOGIN) || !!(process.env.SEED_USER_EMAIL && process.env.SEED_USER_PASSWORD);
 
@@ -25,6 +27,54 @@ const githubProvider = githubLoginEnabled
     })
   : undefined;
 
+//generic OpenID Connect provider (Okta, Google Workspace, Keycloak, etc.) configured via discovery endpoint
+const oidcProvider: OAuthConfig<any> | undefined = oidcLoginEnabled
+  ? {
+      id: "oidc",
+      name: process.env.AUTH_OIDC_NAME || "SSO",
+      type: "oauth",
+      wellKnown: `${(process.env.AUTH_OIDC_ISSUER as string).replace(/\/$/, "")}/.well-known/openid-configuration`,
+      clientId: requireDefined(process.env.AUTH_OIDC_CLIENT_ID, "env AUTH_OIDC_CLIENT_ID is not defined"),
+      clientSecret: requireDefined(process.env.AUTH_OIDC_CLIENT_SECRET, "env AUTH_OIDC_CLIENT_SECRET is not defined"),
+      authorization: { params: { scope: "openid email profile" } },
+      idToken: true,
+      checks: ["pkce", "state"],
+      profile(profile) {
+        return {
+          id: profile.sub,
+          name: profile.name || profile.preferred_username || profile.email,
+          email: profile.email,
+        };
+      },
+    }
+  : undefined;
+
+//comma separated list of email domains allowed to sign in with OIDC. Any account of the IdP is allowed if not set
+const oidcAllowedDomains = (process.env.AUTH_OIDC_ALLOWED_DOMAINS || "")
+  .split(",")
+  .map(d => d.trim().toLowerCase())
+  .filter(d => !!d);
+
+/**
+ * Returns error code if OIDC profile is not allowed to sign in. The code is shown on the sign-in page
+ */
+function checkOidcProfile(profile: any): string | undefined {
+  const email: string | undefined = profile?.email;
+  if (!email) {
+    return "OidcEmailMissing";
+  }
+  if (profile.email_verified === false || profile.email_verified === "false") {
+    return "OidcEmailNotVerified";
+  }
+  if (oidcAllowedDomains.length > 0) {
+    const domain = email.split("@").pop()?.toLowerCase() || "";
+    if (!oidcAllowedDomains.includes(domain)) {
+      return "OidcDomainNotAllowed";
+    }
+  }
+  return undefined;
+}
+
 function toId(email: string) {
   return hash("sha256", email.toLowerCase().trim());
 }
@@ -143,7 +193,7 @@ function generateSecret(base: (string | undefined)[]) {
 
 export const nextAuthConfig: NextAuthOptions = {
   // Configure one or more authentication providers
-  providers: [githubProvider, credentialsProvider].filter(provider => !!provider) as any,
+  providers: [githubProvider, oidcProvider, credentialsProvider].filter(provider => !!provider) as any,
   pages: {
     error: "/error/auth", // Error code passed in query string as ?error=
     signIn: "/signin", // Displays signin buttons
@@ -159,6 +209,16 @@ export const nextAuthConfig: NextAuthOptions = {
       process.env.REDIS_URL,
     ]),
   callbacks: {
+    async signIn({ account, profile }) {
+      if (account?.provider === "oidc") {
+        const error = checkOidcProfile(profile);
+        if (error) {
+          log.atWarn().log(`OIDC sign in of ${profile?.email || profile?.sub} is rejected: ${error}`);
+          return `/signin?error=${error}`;
+        }
+      }
+      return true;
+    },
     jwt: async props => {
       const loginProvider = (props.account?.provider || props.token.loginProvider || "credentials") as string;
       const externalId = requireDefined(props.token.sub, `JWT token .sub is not defined`);
@@ -11,6 +11,7 @@ import mixpanelIcon from "./icons/mixpanel";
 import facebookIcon from "./icons/facebook";
 import juneIcon from "./icons/june";
 import blazeIcon from "./icons/blaze";
+import customerioIcon from "./icons/customerio";
 import mongodbIcon from "./icons/mongodb";
 
 import ga4Icon from "./icons/ga4";
@@ -101,7 +102,15 @@ export const FunctionsConnectionOptions = z.object({
 export const CloudDestinationsConnectionOptions = z
   .object({
     multithreading: z.boolean().optional(),
+    //throughput caps of the connection, enforced by each rotor instance separately. Events exceeding the cap are
+    //delayed, not rejected
+    maxEventsPerSecond: z.number().positive().optional(),
+    maxRequestsPerSecond: z.number().positive().optional(),
+    //HTTP requests written to connection's log: all, failed only or none (unless function is in debug mode)
+    fetchLogLevel: z.enum(["info", "error", "debug"]).optional(),
   })
+  //overrides rotor's default retry policy for failed events
+  .merge(meta.ConnectionRetryOptions)
   .merge(FunctionsConnectionOptions);
 export type CloudDestinationsConnectionOptions = z.infer<typeof CloudDestinationsConnectionOptions>;
 
@@ -143,8 +152,10 @@ export const BaseBulkerConnectionOptions = z
       .default("segment-single-table"),
     schemaFreeze: z.boolean().default(false),
     keepOriginalNames: z.boolean().default(false),
+    columnTypes: z.string().optional(),
   })
   .merge(BatchModeOptions)
+  .merge(meta.ConnectionRetryOptions)
   .merge(FunctionsConnectionOptions);
 
 export type BaseBulkerConnectionOptions = z.infer<typeof BaseBulkerConnectionOptions>;
@@ -707,6 +718,17 @@ export const coreDestinations: DestinationType<any>[] = [
     credentials: meta.BrazeCredentials,
     description: "Braze is a customer engagement platform used by businesses for multichannel marketing.",
   },
+  {
+    id: "customerio",
+    icon: customerioIcon,
+    title: "Customer.io",
+    tags: "Messaging",
+    connectionOptions: CloudDestinationsConnectionOptions,
+    credentials: meta.CustomerioCredentials,
+    credentialsUi: meta.CustomerioCredentialsUi,
+    description:
+      "Customer.io is a messaging platform that sends targeted emails, push notifications and SMS based on user behavior.",
+  },
   {
     id: "mongodb",
     icon: mongodbIcon,
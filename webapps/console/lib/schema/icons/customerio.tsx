@@ -0,0 +1,12 @@
+export default (
+  <svg width="100%" height="100%" viewBox="0 0 256 256" version="1.1" preserveAspectRatio="xMidYMid">
+    <g>
+      <path
+        d="M128,40 C176.601058,40 216,79.3989423 216,128 L216,216 L176,216 L176,128 C176,101.490332 154.509668,80 128,80 C101.490332,80 80,101.490332 80,128 C80,154.509668 101.490332,176 128,176 L148,176 L148,216 L128,216 C79.3989423,216 40,176.601058 40,128 C40,79.3989423 79.3989423,40 128,40 Z"
+        fill="#7131FF"
+        fillRule="nonzero"
+      ></path>
+      <circle fill="#AF64FF" cx="128" cy="128" r="24"></circle>
+    </g>
+  </svg>
+);
@@ -4,12 +4,12 @@ import { Button, Input } from "antd";
 import { useAppConfig } from "../lib/context";
 import { AlertTriangle } from "lucide-react";
 import Link from "next/link";
-import { GithubOutlined } from "@ant-design/icons";
+import { GithubOutlined, LoginOutlined } from "@ant-design/icons";
 import React, { useState } from "react";
 import { feedbackError } from "../lib/ui";
 import { useRouter } from "next/router";
 import { branding } from "../lib/branding";
-import { credentialsLoginEnabled, githubLoginEnabled } from "../lib/nextauth.config";
+import { credentialsLoginEnabled, githubLoginEnabled, oidcLoginEnabled } from "../lib/nextauth.config";
 import { useQuery } from "@tanstack/react-query";
 
 function JitsuLogo() {
@@ -92,7 +92,40 @@ function GitHubSignIn() {
   );
 }
 
-const NextAuthSignInPage = ({ csrfToken, providers: { github, credentials } }) => {
+function OidcSignIn({ name }: { name: string }) {
+  const [loading, setLoading] = useState(false);
+  const router = useRouter();
+  return (
+    <div className="space-y-4">
+      <Button
+        className="w-full"
+        icon={<LoginOutlined />}
+        loading={loading}
+        onClick={async () => {
+          try {
+            setLoading(true);
+            await signIn("oidc");
+            await router.push("/");
+          } catch (e: any) {
+            feedbackError(`Failed to sign in with ${name}`, e);
+          } finally {
+            setLoading(false);
+          }
+        }}
+      >
+        Sign in with {name}
+      </Button>
+    </div>
+  );
+}
+
+const signInErrors: Record<string, string> = {
+  OidcEmailMissing: "Your identity provider didn't share an email address. Ask your administrator to add email claim.",
+  OidcEmailNotVerified: "Email address of your account is not verified by the identity provider.",
+  OidcDomainNotAllowed: "Sign in with accounts of your email domain is not allowed for this instance.",
+};
+
+const NextAuthSignInPage = ({ csrfToken, providers: { github, oidc, credentials } }) => {
   const router = useRouter();
   const nextAuthSession = useSession();
   const app = useAppConfig();
@@ -119,12 +152,18 @@ const NextAuthSignInPage = ({ csrfToken, providers: { github, credentials } }) =
       </div>
       <div>
         {credentials.enabled && <CredentialsForm />}
-        {credentials.enabled && github.enabled && <hr className="my-8" />}
+        {credentials.enabled && (github.enabled || oidc.enabled) && <hr className="my-8" />}
+        {oidc.enabled && <OidcSignIn name={oidc.name} />}
+        {oidc.enabled && github.enabled && <div className="my-4" />}
         {github.enabled && <GitHubSignIn />}
       </div>
       {router.query.error && (
         <div className="text-error">
-          Something went wrong. Please try again. Error code: <code>{router.query.error}</code>
+          {signInErrors[router.query.error as string] || (
+            <>
+              Something went wrong. Please try again. Error code: <code>{router.query.error}</code>
+            </>
+          )}
         </div>
       )}
       {!app.disableSignup && github.enabled && (
@@ -142,8 +181,8 @@ export async function getServerSideProps(context) {
   if (process.env.FIREBASE_AUTH) {
     throw new Error(`Firebase auth is enabled. This page should not be used.`);
   }
-  if (!githubLoginEnabled && !credentialsLoginEnabled) {
-    throw new Error(`No auth providers are enabled found. Available providers: github, credentials`);
+  if (!githubLoginEnabled && !oidcLoginEnabled && !credentialsLoginEnabled) {
+    throw new Error(`No auth providers are enabled found. Available providers: github, oidc, credentials`);
   }
   return {
     props: {
@@ -155,6 +194,10 @@ export async function getServerSideProps(context) {
         github: {
           enabled: githubLoginEnabled,
         },
+        oidc: {
+          enabled: oidcLoginEnabled,
+          name: process.env.AUTH_OIDC_NAME || "SSO",
+        },
       },
       publicPage: true,
     },